@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/signal"
 	"regexp"
 	"runtime"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/dmlongo/callidus/csp"
@@ -16,10 +20,19 @@ import (
 	"github.com/dmlongo/callidus/decomp"
 )
 
-var cspIn, ht, out string
+var cspIn, ht, htOut, out, resume string
 var decompTime string
 var yMode string
-var subSeq bool
+var rootMode string
+var relImpl string
+var solverKind, solverCmd, solverStdin string
+var solver decomp.Solver
+var memBudget uint64
+var parThreshold int
+var timeout, nodeTime, subTime int
+var nodeMem uint64
+var subSeq, pipeline, cache bool
+var normalize, domFilter, distinct bool
 var htDebug, tabDebug, subDebug, memDebug, solDebug bool
 var subInMem, printRel, printSol, printTimes bool
 var all bool
@@ -36,66 +49,53 @@ var cspName string
 var baseDir string
 
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "hg":
+			hgCommand(os.Args[2:])
+			return
+		case "stats":
+			statsCommand(os.Args[2:])
+			return
+		}
+	}
+
 	defer cleanup()
 	setFlags()
 
-	fmt.Printf("Callidus starts solving %s!\n", cspName)
-	start = time.Now()
-
-	fmt.Print("Creating hypergraph... ")
-	startConversion := time.Now()
-	hypergraph := decomp.Convert(cspIn, baseDir)
-	durConversion := time.Since(startConversion)
-	fmt.Println("done in", durConversion)
-	durs = append(durs, durConversion)
-
-	var rawHypertree string
-	var startDecomposition time.Time
-	var durDecomp time.Duration
-	if ht == "" {
-		hg := baseDir + cspName + ".hg"
-		fmt.Print("Decomposing hypergraph... ")
-		startDecomposition = time.Now()
-		if htDebug {
-			//ht = baseDir + cspName + ".ht"
-			rawHypertree = decomp.DecomposeToFile(hg, baseDir+cspName+".ht", decompTime)
-		} else {
-			rawHypertree = decomp.Decompose(hg, decompTime)
-		}
-		durDecomp = time.Since(startDecomposition)
-		fmt.Println("done in", durDecomp)
+	impl, err := db.ParseImpl(relImpl)
+	if err != nil {
+		panic(err)
 	}
-	durs = append(durs, durDecomp)
-
-	if ht == "" && rawHypertree == "" {
-		fmt.Printf("Could not find any decomposition in %vs\n", decompTime)
-		return
+	db.DefaultImpl = impl
+	if solver, err = decomp.NewSolver(solverKind, solverCmd, solverStdin); err != nil {
+		panic(err)
 	}
-
-	fmt.Print("Parsing hypertree, domains and constraints... ")
-	startParsing := time.Now()
-
-	var tree decomp.Hypertree
-	var root *decomp.Node
-	if ht != "" {
-		root, tree = decomp.ParseGML(ht)
-		// TODO check if decomp is correct for hg
-	} else {
-		root, tree = decomp.ParseBalancedGo(&rawHypertree)
+	decomp.ParThreshold = parThreshold
+	decomp.DistinctTables = distinct
+	if memBudget > 0 || impl == db.DiskImpl {
+		spillFolder := baseDir + "spill/"
+		if err := os.MkdirAll(spillFolder, 0777); err != nil {
+			panic(err)
+		}
+		db.SetMemoryBudget(memBudget<<20, spillFolder)
 	}
-	tree.Complete(hypergraph)
-
-	var domains map[string]string
-	domFile := baseDir + cspName + ".dom"
-	domains = csp.ParseDomains(domFile)
 
-	var constraints map[string]csp.Constraint
-	ctrFile := baseDir + cspName + ".ctr"
-	constraints = csp.ParseConstraints(ctrFile)
+	fmt.Printf("Callidus starts solving %s!\n", cspName)
+	start = time.Now()
 
-	durParsing := time.Since(startParsing)
-	fmt.Println("done in", durParsing)
-	durs = append(durs, durParsing)
+	// children are killed when the context is done, since they do not get our signals
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+		defer cancel()
+	}
+	go func() {
+		<-ctx.Done()
+		stop() // a second signal kills Callidus
+	}()
 
 	if memDebug {
 		go func() {
@@ -106,36 +106,36 @@ func main() {
 		}()
 	}
 
-	var satisfiable bool
-	fmt.Print("Solving sub-CSPs... ")
-	startSubComp := time.Now()
-	if subSeq {
-		satisfiable = decomp.SolveSubCspSeq(tree, domains, constraints, baseDir)
+	var root *decomp.Node
+	var tree decomp.Hypertree
+	if resume != "" {
+		fmt.Print("Loading hypertree and tables... ")
+		startLoading := time.Now()
+		root, tree = decomp.LoadTables(resume)
+		durLoading := time.Since(startLoading)
+		fmt.Println("done in", durLoading)
+		durs = append(durs, 0, 0, durLoading, 0)
 	} else {
-		satisfiable = decomp.SolveSubCspPar(tree, domains, constraints, baseDir)
+		var ok bool
+		if root, tree, ok = computeTables(ctx); !ok {
+			return
+		}
 	}
-	durSubComp := time.Since(startSubComp)
-	fmt.Println("done in", durSubComp)
-	durs = append(durs, durSubComp)
-	if !satisfiable {
-		printOutput(satisfiable)
-		return
+
+	switch rootMode {
+	case "first":
+	case "center":
+		root = tree.Center()
+		tree.Reroot(root)
+	case "cost":
+		root = tree.BestRoot(decomp.TableCost)
+		tree.Reroot(root)
+	default:
+		panic(fmt.Sprintf("%v root selection not implemented", rootMode))
 	}
 
-	if tabDebug {
-		tablesFolder := baseDir + "tables/"
-		err := os.RemoveAll(tablesFolder)
-		if err != nil {
-			panic(err)
-		}
-		err = os.Mkdir(tablesFolder, 0777)
-		if err != nil {
-			panic(err)
-		}
-		for _, node := range tree {
-			tableFile := tablesFolder + "sub" + strconv.Itoa(node.ID) + ".tab"
-			db.RelToFile(tableFile, node.Table)
-		}
+	if htOut != "" {
+		tree.WriteToFile(htOut)
 	}
 
 	if printRel {
@@ -148,10 +148,14 @@ func main() {
 	}
 	fmt.Print("Running Yannakakis... ") // TODO the csp can be unsat also here
 	startYannakakis := time.Now()
-	sol, satisfiable := y.Solve()
+	sol, satisfiable, err := y.Solve(ctx)
 	durYannakakis := time.Since(startYannakakis)
-	fmt.Println("done in", durYannakakis)
 	durs = append(durs, durYannakakis)
+	if err != nil {
+		printStopped("running Yannakakis", err)
+		return
+	}
+	fmt.Println("done in", durYannakakis)
 	if !satisfiable {
 		printOutput(satisfiable)
 		return
@@ -167,10 +171,14 @@ func main() {
 	if all {
 		fmt.Print("Computing all solutions... ")
 		startComputeAll := time.Now()
-		solutions = y.AllSolutions()
+		solutions, err = y.AllSolutions(ctx)
 		durComputeAll := time.Since(startComputeAll)
-		fmt.Println("done in", durComputeAll)
 		durs = append(durs, durComputeAll)
+		if err != nil {
+			printStopped("computing all solutions", err)
+			return
+		}
+		fmt.Println("done in", durComputeAll)
 
 		if printRel {
 			decomp.PrintTreeRelations(root)
@@ -195,6 +203,130 @@ func main() {
 	}
 }
 
+// computeTables decomposes the CSP and solves the sub-CSP of each node of the hypertree.
+// It returns false if there is no decomposition, a sub-CSP is unsatisfiable or ctx is done.
+func computeTables(ctx context.Context) (root *decomp.Node, tree decomp.Hypertree, ok bool) {
+	fmt.Print("Creating hypergraph... ")
+	startConversion := time.Now()
+	hypergraph, err := decomp.Convert(ctx, cspIn, baseDir)
+	durConversion := time.Since(startConversion)
+	durs = append(durs, durConversion)
+	if err != nil {
+		printStopped("creating the hypergraph", err)
+		return nil, nil, false
+	}
+	fmt.Println("done in", durConversion)
+
+	var rawHypertree string
+	var startDecomposition time.Time
+	var durDecomp time.Duration
+	if ht == "" {
+		hg := baseDir + cspName + ".hg"
+		fmt.Print("Decomposing hypergraph... ")
+		startDecomposition = time.Now()
+		if htDebug {
+			//ht = baseDir + cspName + ".ht"
+			rawHypertree, err = decomp.DecomposeToFile(ctx, hg, baseDir+cspName+".ht", decompTime)
+		} else {
+			rawHypertree, err = decomp.Decompose(ctx, hg, decompTime)
+		}
+		durDecomp = time.Since(startDecomposition)
+		if err != nil {
+			durs = append(durs, durDecomp)
+			printStopped("decomposing the hypergraph", err)
+			return nil, nil, false
+		}
+		fmt.Println("done in", durDecomp)
+	}
+	durs = append(durs, durDecomp)
+
+	if ht == "" && rawHypertree == "" {
+		fmt.Printf("Could not find any decomposition in %vs\n", decompTime)
+		return nil, nil, false
+	}
+
+	fmt.Print("Parsing hypertree, domains and constraints... ")
+	startParsing := time.Now()
+
+	if ht != "" {
+		root, tree = decomp.ParseHypertree(ht)
+	} else {
+		root, tree = decomp.ParseBalancedGo(&rawHypertree)
+	}
+	tree.Complete(hypergraph)
+	if normalize {
+		root = tree.Normalize()
+	}
+	if ht != "" {
+		if err := tree.Validate(hypergraph); err != nil {
+			panic(fmt.Sprintf("%s is not a decomposition of %s: %v", ht, cspIn, err))
+		}
+	}
+
+	var domains map[string]string
+	domFile := baseDir + cspName + ".dom"
+	domains = csp.ParseDomains(domFile)
+
+	var constraints map[string]csp.Constraint
+	ctrFile := baseDir + cspName + ".ctr"
+	constraints = csp.ParseConstraints(ctrFile)
+
+	if domains, ok = filterDomains(constraints, domains); !ok {
+		fmt.Println("done in", time.Since(startParsing))
+		printOutput(false)
+		return nil, nil, false
+	}
+
+	durParsing := time.Since(startParsing)
+	fmt.Println("done in", durParsing)
+	durs = append(durs, durParsing)
+
+	var satisfiable bool
+	opts := decomp.SubOptions{
+		Solver:      solver,
+		KeepFiles:   subDebug,
+		NodeTimeout: time.Duration(nodeTime) * time.Second,
+		Timeout:     time.Duration(subTime) * time.Second,
+		NodeMemory:  nodeMem << 20,
+	}
+	if cache {
+		opts.CacheDir = wrkdir + "/cache/"
+	}
+	fmt.Print("Solving sub-CSPs... ")
+	startSubComp := time.Now()
+	if subSeq {
+		satisfiable, err = decomp.SolveSubCspSeq(ctx, tree, domains, constraints, baseDir, opts)
+	} else if pipeline {
+		satisfiable, err = decomp.SolveAndReduce(ctx, root, domains, constraints, baseDir, opts)
+	} else {
+		satisfiable, err = decomp.SolveSubCspPar(ctx, tree, domains, constraints, baseDir, opts)
+	}
+	durSubComp := time.Since(startSubComp)
+	durs = append(durs, durSubComp)
+	if err != nil {
+		printStopped("solving sub-CSPs", err)
+		return nil, nil, false
+	}
+	fmt.Println("done in", durSubComp)
+	if !satisfiable {
+		printOutput(satisfiable)
+		return nil, nil, false
+	}
+
+	if tabDebug {
+		decomp.SaveTables(tree, baseDir+"tables/")
+	}
+	return root, tree, true
+}
+
+// filterDomains shrinks the domains with csp.FilterDomains, unless -domFilter is off
+func filterDomains(constraints map[string]csp.Constraint, domains map[string]string) (map[string]string, bool) {
+	if !domFilter {
+		return domains, true
+	}
+	return csp.FilterDomains(constraints, domains)
+}
+
 func printOutput(sat bool) {
 	durCallidus := time.Since(start)
 	numSols = len(solutions)
@@ -219,49 +351,90 @@ func printOutput(sat bool) {
 	}
 
 	if printTimes {
-		//durs := []time.Duration{durConversion, durDecomp, durParsing, durSubComp, durYannakakis, durComputeAll, durSolvingAll}
-		for i := len(durs); i < 6; i++ {
-			durs = append(durs, 0)
-		}
-		durs = append(durs, durCallidus)
-
-		var sb strings.Builder
-		for _, d := range durs {
-			sb.WriteString(strconv.Itoa(int(d.Milliseconds())))
-			sb.WriteString(";")
-		}
-
+		var res string
 		if !all {
 			if !sat {
-				sb.WriteString("n")
+				res = "n"
 			} else {
-				sb.WriteString("y")
+				res = "y"
 			}
 		} else {
-			sb.WriteString(strconv.Itoa(numSols))
+			res = strconv.Itoa(numSols)
 		}
+		printDurations(durCallidus, res)
+	}
+}
+
+// printStopped reports the phase in which Callidus stopped before solving the CSP
+func printStopped(phase string, err error) {
+	durCallidus := time.Since(start)
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		err = fmt.Errorf("timeout after %vs", timeout)
+	case errors.Is(err, context.Canceled):
+		err = errors.New("interrupted")
+	}
+	fmt.Println()
+	fmt.Println(cspIn, "not solved:", err)
+	fmt.Println("Callidus stopped while", phase, "after", durCallidus)
 
-		fmt.Println("convert;decomp;parsing;subcsp;yanna;compall;total;sols")
-		fmt.Println(sb.String())
+	if printTimes {
+		printDurations(durCallidus, "?")
 	}
 }
 
+func printDurations(durCallidus time.Duration, sols string) {
+	//durs := []time.Duration{durConversion, durDecomp, durParsing, durSubComp, durYannakakis, durComputeAll, durSolvingAll}
+	for i := len(durs); i < 6; i++ {
+		durs = append(durs, 0)
+	}
+	durs = append(durs, durCallidus)
+
+	var sb strings.Builder
+	for _, d := range durs {
+		sb.WriteString(strconv.Itoa(int(d.Milliseconds())))
+		sb.WriteString(";")
+	}
+	sb.WriteString(sols)
+
+	fmt.Println("convert;decomp;parsing;subcsp;yanna;compall;total;sols")
+	fmt.Println(sb.String())
+}
+
 func setFlags() {
 	flagSet := flag.NewFlagSet("", flag.ContinueOnError)
 	flagSet.SetOutput(ioutil.Discard) //todo: see what happens without this line
 
 	flagSet.StringVar(&cspIn, "csp", "", "Path to the CSP to solve (XCSP3 format)")
-	flagSet.StringVar(&ht, "ht", "", "Path to a decomposition of the CSP to solve (GML format)")
+	flagSet.StringVar(&ht, "ht", "", "Path to a decomposition of the CSP to solve (GML, PACE or BalancedGo format)")
+	flagSet.StringVar(&htOut, "htOut", "", "Save the hypertree used to solve the CSP (GML, DOT or TD format, by extension)")
+	flagSet.StringVar(&resume, "resume", "", "Resume from the hypertree and tables saved by -tabDebug in the specified folder")
 	flagSet.StringVar(&out, "out", "", "Save the solutions of the CSP into the specified file")
 	flagSet.StringVar(&decompTime, "decompTime", "3600", "Set a timeout (seconds) for computing a decomposition of the CSP")
 	flagSet.StringVar(&yMode, "yMode", "par", "Set Yannakakis'algorithm mode: seq, par, ymca")
+	flagSet.StringVar(&rootMode, "root", "first", "Set how the root of the hypertree is chosen: first, center, cost")
+	flagSet.BoolVar(&normalize, "normalize", true, "Merge redundant nodes of the hypertree before solving")
+	flagSet.BoolVar(&domFilter, "domFilter", true, "Shrink domains with unary constraints and small tables before solving sub-CSPs")
+	flagSet.StringVar(&relImpl, "relImpl", "row", "Set how relations store their tuples: row, flat, disk")
+	flagSet.BoolVar(&distinct, "distinct", false, "Drop duplicate tuples while solving sub-CSPs, keeping a hash index per table (otherwise once they are solved)")
+	flagSet.Uint64Var(&memBudget, "memBudget", 0, "Move relations to disk when the heap exceeds this size (MiB, 0 for no limit)")
+	flagSet.IntVar(&parThreshold, "parThreshold", decomp.ParThreshold, "Use parallel semijoins and joins in par mode when two tables have more tuples than this")
+	flagSet.StringVar(&solverKind, "solver", "nacre", "Set the solver of sub-CSPs: nacre, xcsp (any solver printing XCSP3 v lines)")
+	flagSet.StringVar(&solverCmd, "solverCmd", "", "Command line of the solver of sub-CSPs, with {} in place of the file (e.g., \"java -jar ACE.jar {} -s=all\")")
+	flagSet.StringVar(&solverStdin, "solverStdin", "", "Argument of -solverCmd in place of {} to stream sub-CSPs to the solver (e.g., /dev/stdin or -)")
+	flagSet.IntVar(&timeout, "timeout", 0, "Set a timeout (seconds) for the whole run, killing the external tools (0 for no limit)")
+	flagSet.IntVar(&nodeTime, "nodeTime", 0, "Set a timeout (seconds) for solving each sub-CSP (0 for no limit)")
+	flagSet.IntVar(&subTime, "subTime", 0, "Set a timeout (seconds) for solving all sub-CSPs (0 for no limit)")
+	flagSet.Uint64Var(&nodeMem, "nodeMem", 0, "Kill the solver of a sub-CSP when it uses more memory than this (MiB, 0 for no limit)")
 	flagSet.BoolVar(&all, "all", false, "Compute all solutions of the CSP")
 	flagSet.BoolVar(&htDebug, "htDebug", false, "Write hypertree on disk for debug (false if -ht is set)")
-	flagSet.BoolVar(&subDebug, "subDebug", false, "Write sub-CSP files on disk for debug") // TODO update
-	flagSet.BoolVar(&tabDebug, "tabDebug", false, "Save solutions of sb-CSPs on disk for debug")
+	flagSet.BoolVar(&subDebug, "subDebug", false, "Keep sub-CSP files on disk for debug, instead of streaming them to the solver")
+	flagSet.BoolVar(&tabDebug, "tabDebug", false, "Save solutions of sub-CSPs on disk (binary format) for debug")
 	flagSet.BoolVar(&memDebug, "memDebug", false, "Print memory usage every 5sseconds")
 	flagSet.BoolVar(&subInMem, "subInMem", false, "Activate in-memory computation of sub-CSPs")
 	flagSet.BoolVar(&subSeq, "subSeq", false, "Activate sequential computation of sub-CSPs")
+	flagSet.BoolVar(&cache, "cache", false, "Reuse the tables of sub-CSPs equal up to renaming, across nodes and runs (stored in "+wrkdir+"/cache/)")
+	flagSet.BoolVar(&pipeline, "pipeline", false, "Semijoin sub-CSPs into their parents while the others are still being solved")
 	//flagSet.BoolVar(&ySeq, "ySeq", false, "Use sequential Yannakakis' algorithm")
 	flagSet.BoolVar(&solDebug, "solDebug", false, "Check solutions of the CSP")
 	flagSet.BoolVar(&printRel, "printRel", false, "Print relations at every step of the CSP resolution")
@@ -319,9 +492,11 @@ func setFlags() {
 	//if ht == "" {ht = "output" + folder + "hypertree"}
 	//fmt.Printf("csp=%v\nht=%v\nout=%v\n", csp, ht, out)
 
-	err := os.RemoveAll(baseDir) // TODO removing wastes time, not necessary
-	if err != nil {
-		panic(err)
+	if resume == "" {
+		err := os.RemoveAll(baseDir) // TODO removing wastes time, not necessary
+		if err != nil {
+			panic(err)
+		}
 	}
 
 	numSols = 0
@@ -343,7 +518,7 @@ func bToMb(b uint64) uint64 {
 }
 
 func cleanup() {
-	if !subDebug {
+	if !subDebug && !tabDebug && resume == "" {
 		err := os.RemoveAll(baseDir)
 		if err != nil {
 			panic(err)
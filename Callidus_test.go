@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/dmlongo/callidus/csp"
+)
+
+func TestFilterDomainsFlag(t *testing.T) {
+	ctrs := map[string]csp.Constraint{
+		"c1": csp.NewExtensionCtr("c1", []string{"x"}, [][]int{{1}, {2}, {7}}),
+		"c2": csp.NewExtensionCtr("c2", []string{"x", "y"}, [][]int{{1, 4}, {5, 5}}),
+	}
+	doms := map[string]string{"x": "0..3", "y": "0..9"}
+	defer func(on bool) { domFilter = on }(domFilter)
+
+	domFilter = false
+	res, ok := filterDomains(ctrs, doms)
+	if !ok || res["x"] != "0..3" || res["y"] != "0..9" {
+		t.Errorf("domains = %v, %v without filtering, expected %v", res, ok, doms)
+	}
+
+	domFilter = true
+	res, ok = filterDomains(ctrs, doms)
+	if !ok || res["x"] != "1" || res["y"] != "4" {
+		t.Errorf("domains = %v, %v with filtering, expected x=1 and y=4", res, ok)
+	}
+}
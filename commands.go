@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/dmlongo/callidus/csp"
+	"github.com/dmlongo/callidus/decomp"
+)
+
+// hgCommand converts hypergraphs between the supported formats
+func hgCommand(args []string) {
+	var in, cspPath, hgOut string
+	flagSet := flag.NewFlagSet("hg", flag.ExitOnError)
+	flagSet.StringVar(&in, "in", "", "Path to the hypergraph to convert (HyperBench, PACE .hgr or .json)")
+	flagSet.StringVar(&cspPath, "csp", "", "Path to a CSP whose hypergraph is converted (XCSP3 format)")
+	flagSet.StringVar(&hgOut, "out", "", "Path to the converted hypergraph (HyperBench, PACE .hgr or .json, by extension)")
+	flagSet.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage of Callidus hg (https://github.com/dmlongo/Callidus)")
+		flagSet.PrintDefaults()
+	}
+	if err := flagSet.Parse(args); err != nil || (in == "") == (cspPath == "") || hgOut == "" {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	var hg decomp.Hypergraph
+	if cspPath != "" {
+		tmpDir, err := ioutil.TempDir("", "callidus")
+		if err != nil {
+			panic(err)
+		}
+		defer func() {
+			if err := os.RemoveAll(tmpDir); err != nil {
+				panic(err)
+			}
+		}()
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+		if hg, err = decomp.Convert(ctx, cspPath, tmpDir+"/"); err != nil {
+			fmt.Fprintln(os.Stderr, "Could not convert", cspPath+":", err)
+			return
+		}
+	} else {
+		hg = decomp.ReadHypergraph(in)
+	}
+	hg.WriteToFile(hgOut)
+	fmt.Println("Hypergraph with", len(hg), "edges written to", hgOut)
+}
+
+// statsCommand prints statistics of the hypergraph and of the hypertree of a CSP without solving it
+func statsCommand(args []string) {
+	var cspPath, htPath, timeout string
+	var asJSON, normalize bool
+	flagSet := flag.NewFlagSet("stats", flag.ExitOnError)
+	flagSet.StringVar(&cspPath, "csp", "", "Path to the CSP to analyze (XCSP3 format)")
+	flagSet.StringVar(&htPath, "ht", "", "Path to a decomposition of the CSP (GML, PACE or BalancedGo format)")
+	flagSet.StringVar(&timeout, "decompTime", "3600", "Set a timeout (seconds) for computing a decomposition of the CSP")
+	flagSet.BoolVar(&normalize, "normalize", true, "Merge redundant nodes of the hypertree, as when solving")
+	flagSet.BoolVar(&asJSON, "json", false, "Print statistics as JSON")
+	flagSet.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage of Callidus stats (https://github.com/dmlongo/Callidus)")
+		flagSet.PrintDefaults()
+	}
+	if err := flagSet.Parse(args); err != nil || cspPath == "" {
+		flagSet.Usage()
+		os.Exit(1)
+	}
+
+	tmpDir, err := ioutil.TempDir("", "callidus")
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err := os.RemoveAll(tmpDir); err != nil {
+			panic(err)
+		}
+	}()
+	outDir := tmpDir + "/"
+	name := filepath.Base(cspPath)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	hypergraph, err := decomp.Convert(ctx, cspPath, outDir)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Could not convert", cspPath+":", err)
+		return
+	}
+
+	var tree decomp.Hypertree
+	if htPath != "" {
+		_, tree = decomp.ParseHypertree(htPath)
+	} else {
+		rawHypertree, err := decomp.Decompose(ctx, outDir+name+".hg", timeout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Could not decompose", cspPath+":", err)
+			return
+		}
+		if rawHypertree == "" {
+			fmt.Fprintf(os.Stderr, "Could not find any decomposition in %vs\n", timeout)
+			os.Exit(1)
+		}
+		_, tree = decomp.ParseBalancedGo(&rawHypertree)
+	}
+	tree.Complete(hypergraph)
+	if normalize {
+		tree.Normalize()
+	}
+	domains := csp.ParseDomains(outDir + name + ".dom")
+
+	stats := struct {
+		Hypergraph decomp.HypergraphStats `json:"hypergraph"`
+		Hypertree  decomp.HypertreeStats  `json:"hypertree"`
+	}{hypergraph.Stats(), tree.Stats(domains)}
+
+	if asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(stats); err != nil {
+			panic(err)
+		}
+		return
+	}
+
+	hs, ts := stats.Hypergraph, stats.Hypertree
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "vertices\t%v\n", hs.Vertices)
+	fmt.Fprintf(w, "edges\t%v\n", hs.Edges)
+	fmt.Fprintf(w, "max edge size\t%v\n", hs.MaxEdgeSize)
+	fmt.Fprintf(w, "avg edge size\t%.2f\n", hs.AvgEdgeSize)
+	fmt.Fprintf(w, "max degree\t%v\n", hs.MaxDegree)
+	fmt.Fprintf(w, "avg degree\t%.2f\n", hs.AvgDegree)
+	fmt.Fprintf(w, "degrees\t%v\n", hs.Degrees)
+	fmt.Fprintf(w, "nodes\t%v\n", ts.Nodes)
+	fmt.Fprintf(w, "width\t%v\n", ts.Width)
+	fmt.Fprintf(w, "depth\t%v\n", ts.Depth)
+	fmt.Fprintf(w, "max bag size\t%v\n", ts.MaxBagSize)
+	fmt.Fprintf(w, "avg bag size\t%.2f\n", ts.AvgBagSize)
+	fmt.Fprintf(w, "max table size\t%.4g\n", ts.MaxTableSize)
+	fmt.Fprintf(w, "sum table size\t%.4g\n", ts.SumTableSize)
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,68 @@
+package csp
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// delimiters of the tokens of an XCSP instance that may be variables
+const xcspDelims = " \t\r\n(),<>=\""
+
+// CanonicalForm renames the variables of an XCSP instance written by
+// CreateXCSPInstance in order of first occurrence in its constraints, so that
+// two instances that differ only by the names of their variables have the
+// same canonical form. It also returns the renaming.
+func CanonicalForm(instance string, variables map[string]string) (string, map[string]string) {
+	renaming := make(map[string]string)
+	rename := func(tk string) string {
+		if _, ok := variables[tk]; !ok {
+			return tk
+		}
+		if _, ok := renaming[tk]; !ok {
+			renaming[tk] = "V" + strconv.Itoa(len(renaming))
+		}
+		return renaming[tk]
+	}
+
+	var sb strings.Builder
+	if start := strings.Index(instance, "<constraints>"); start >= 0 {
+		renameTokens(&sb, instance[start:], rename)
+	}
+	var unused []string
+	for v := range variables {
+		if _, ok := renaming[v]; !ok {
+			unused = append(unused, v)
+		}
+	}
+	sort.Strings(unused)
+	for _, v := range unused {
+		rename(v)
+	}
+
+	vars := make([]string, len(renaming))
+	for v, canon := range renaming {
+		i, _ := strconv.Atoi(canon[1:])
+		vars[i] = v
+	}
+	for _, v := range vars {
+		sb.WriteString(renaming[v] + " " + strings.TrimSpace(variables[v]) + "\n")
+	}
+	return sb.String(), renaming
+}
+
+func renameTokens(sb *strings.Builder, s string, rename func(tk string) string) {
+	start := 0
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) && !strings.ContainsRune(xcspDelims, rune(s[i])) {
+			continue
+		}
+		if start < i {
+			sb.WriteString(rename(s[start:i]))
+		}
+		if i < len(s) {
+			sb.WriteByte(s[i])
+		}
+		start = i + 1
+	}
+}
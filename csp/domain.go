@@ -0,0 +1,88 @@
+package csp
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DomainValues lists the values of a domain in XCSP format (e.g., "0..3 7 9")
+func DomainValues(dom string) []int {
+	var vals []int
+	for _, tk := range strings.Fields(dom) {
+		lo, hi := domainInterval(tk)
+		for v := lo; v <= hi; v++ {
+			vals = append(vals, v)
+		}
+	}
+	return vals
+}
+
+// DomainSize counts the values of a domain in XCSP format (e.g., "0..3 7 9")
+func DomainSize(dom string) int {
+	size := 0
+	for _, tk := range strings.Fields(dom) {
+		lo, hi := domainInterval(tk)
+		size += hi - lo + 1
+	}
+	return size
+}
+
+func domainInterval(tk string) (int, int) {
+	if i := strings.Index(tk, ".."); i >= 0 {
+		lo, err := strconv.Atoi(tk[:i])
+		if err != nil {
+			panic("Bad domain interval: " + tk)
+		}
+		hi, err := strconv.Atoi(tk[i+2:])
+		if err != nil {
+			panic("Bad domain interval: " + tk)
+		}
+		return lo, hi
+	}
+	v, err := strconv.Atoi(tk)
+	if err != nil {
+		panic("Bad domain value: " + tk)
+	}
+	return v, v
+}
+
+// Domain is a set of values stored as sorted intervals
+type Domain [][2]int
+
+// ParseDomain in XCSP format (e.g., "0..3 7 9")
+func ParseDomain(dom string) Domain {
+	var d Domain
+	for _, tk := range strings.Fields(dom) {
+		lo, hi := domainInterval(tk)
+		d = append(d, [2]int{lo, hi})
+	}
+	sort.Slice(d, func(i, j int) bool { return d[i][0] < d[j][0] })
+	return d
+}
+
+// Contains tells whether v is in this domain
+func (d Domain) Contains(v int) bool {
+	i := sort.Search(len(d), func(i int) bool { return d[i][1] >= v })
+	return i < len(d) && d[i][0] <= v
+}
+
+// FormatDomain writes a set of values in XCSP format, joining consecutive values in intervals
+func FormatDomain(vals []int) string {
+	sorted := append([]int(nil), vals...)
+	sort.Ints(sorted)
+	var tks []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] <= sorted[j]+1 {
+			j++
+		}
+		if sorted[i] == sorted[j] {
+			tks = append(tks, strconv.Itoa(sorted[i]))
+		} else {
+			tks = append(tks, strconv.Itoa(sorted[i])+".."+strconv.Itoa(sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(tks, " ")
+}
@@ -0,0 +1,53 @@
+package csp
+
+import "testing"
+
+func TestDomainValues(t *testing.T) {
+	res := DomainValues("-2..1 5 7..8")
+	expected := []int{-2, -1, 0, 1, 5, 7, 8}
+	if len(res) != len(expected) {
+		t.Fatalf("res= %v; want %v", res, expected)
+	}
+	for i, v := range res {
+		if v != expected[i] {
+			t.Errorf("res[%d]= %v; want %v", i, v, expected[i])
+		}
+	}
+	if size := DomainSize("-2..1 5 7..8"); size != len(expected) {
+		t.Errorf("size= %v; want %v", size, len(expected))
+	}
+}
+
+func TestDomainContains(t *testing.T) {
+	d := ParseDomain("7..8 -2..1 5")
+	for _, v := range []int{-2, 0, 1, 5, 7, 8} {
+		if !d.Contains(v) {
+			t.Errorf("%v not in %v", v, d)
+		}
+	}
+	for _, v := range []int{-3, 2, 4, 6, 9} {
+		if d.Contains(v) {
+			t.Errorf("%v in %v", v, d)
+		}
+	}
+}
+
+func TestExtensionTable(t *testing.T) {
+	c := &extensionCtr{CName: "c", Vars: "x y", CType: "supports", Tuples: "(0,1)(1, 2)(2,0)"}
+	vars, tuples, ok := ExtensionTable(c)
+	if !ok || len(vars) != 2 || len(tuples) != 3 || tuples[1][0] != 1 || tuples[1][1] != 2 {
+		t.Errorf("vars= %v, tuples= %v, ok= %v", vars, tuples, ok)
+	}
+	c = &extensionCtr{CName: "c", Vars: "x", CType: "supports", Tuples: "0..2 5"}
+	if _, tuples, ok := ExtensionTable(c); !ok || len(tuples) != 4 {
+		t.Errorf("tuples= %v, ok= %v", tuples, ok)
+	}
+	c = &extensionCtr{CName: "c", Vars: "x y", CType: "conflicts", Tuples: "(0,1)"}
+	if _, _, ok := ExtensionTable(c); ok {
+		t.Error("conflicts accepted")
+	}
+	c = &extensionCtr{CName: "c", Vars: "x y", CType: "supports", Tuples: "(0,*)"}
+	if _, _, ok := ExtensionTable(c); ok {
+		t.Error("stars accepted")
+	}
+}
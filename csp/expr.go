@@ -0,0 +1,255 @@
+package csp
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Expr is an expression of an intensional constraint in XCSP functional
+// syntax (e.g., "gt(add(x,1),y)"). Booleans are evaluated as 0 and 1.
+type Expr struct {
+	op   string
+	val  int
+	args []*Expr
+}
+
+// ParseExpr parses an expression in XCSP functional syntax
+func ParseExpr(s string) (*Expr, error) {
+	p := exprParser{in: s}
+	e, err := p.parse()
+	if err != nil {
+		return nil, err
+	}
+	if p.skip(); p.pos < len(p.in) {
+		return nil, fmt.Errorf("unexpected %q in %s", p.in[p.pos:], s)
+	}
+	return e, nil
+}
+
+type exprParser struct {
+	in  string
+	pos int
+}
+
+func (p *exprParser) skip() {
+	for p.pos < len(p.in) && strings.ContainsRune(" \t\r\n", rune(p.in[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *exprParser) parse() (*Expr, error) {
+	p.skip()
+	start := p.pos
+	for p.pos < len(p.in) && !strings.ContainsRune("(), \t\r\n", rune(p.in[p.pos])) {
+		p.pos++
+	}
+	tk := p.in[start:p.pos]
+	if tk == "" {
+		return nil, fmt.Errorf("expected a term at %v in %s", start, p.in)
+	}
+	p.skip()
+	if p.pos >= len(p.in) || p.in[p.pos] != '(' {
+		if v, err := strconv.Atoi(tk); err == nil {
+			return &Expr{val: v}, nil
+		}
+		return &Expr{op: tk}, nil // a variable
+	}
+
+	e := &Expr{op: tk, args: []*Expr{}}
+	p.pos++
+	if p.skip(); p.pos < len(p.in) && p.in[p.pos] == ')' { // e.g., set()
+		p.pos++
+		return e, nil
+	}
+	for {
+		arg, err := p.parse()
+		if err != nil {
+			return nil, err
+		}
+		e.args = append(e.args, arg)
+		p.skip()
+		if p.pos >= len(p.in) {
+			return nil, fmt.Errorf("unterminated %s in %s", tk, p.in)
+		}
+		c := p.in[p.pos]
+		p.pos++
+		if c == ')' {
+			return e, nil
+		} else if c != ',' {
+			return nil, fmt.Errorf("unexpected %q at %v in %s", c, p.pos-1, p.in)
+		}
+	}
+}
+
+// Eval evaluates this expression assigning the given values to its variables
+func (e *Expr) Eval(vals map[string]int) (int, error) {
+	if e.args == nil {
+		if e.op == "" {
+			return e.val, nil
+		}
+		v, ok := vals[e.op]
+		if !ok {
+			return 0, fmt.Errorf("no value for %s", e.op)
+		}
+		return v, nil
+	}
+
+	switch e.op {
+	case "in", "notin":
+		if len(e.args) != 2 || e.args[1].op != "set" {
+			return 0, fmt.Errorf("bad %s", e.op)
+		}
+		x, err := e.args[0].Eval(vals)
+		if err != nil {
+			return 0, err
+		}
+		found := false
+		for _, a := range e.args[1].args {
+			v, err := a.Eval(vals)
+			if err != nil {
+				return 0, err
+			}
+			if v == x {
+				found = true
+				break
+			}
+		}
+		return boolToInt(found == (e.op == "in")), nil
+	case "if":
+		if len(e.args) != 3 {
+			return 0, fmt.Errorf("bad if")
+		}
+		c, err := e.args[0].Eval(vals)
+		if err != nil {
+			return 0, err
+		}
+		if c != 0 {
+			return e.args[1].Eval(vals)
+		}
+		return e.args[2].Eval(vals)
+	}
+
+	args := make([]int, len(e.args))
+	for i, a := range e.args {
+		v, err := a.Eval(vals)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+	return applyOp(e.op, args)
+}
+
+// opArity of the operators with a fixed number of arguments
+var opArity = map[string]int{
+	"neg": 1, "abs": 1, "sqr": 1, "not": 1,
+	"sub": 2, "div": 2, "mod": 2, "pow": 2, "dist": 2,
+	"lt": 2, "le": 2, "gt": 2, "ge": 2, "ne": 2, "imp": 2,
+}
+
+func applyOp(op string, args []int) (int, error) {
+	if n, ok := opArity[op]; ok && len(args) != n {
+		return 0, fmt.Errorf("%s expects %v arguments, got %v", op, n, len(args))
+	}
+	if len(args) == 0 {
+		return 0, fmt.Errorf("%s without arguments", op)
+	}
+
+	switch op {
+	case "neg":
+		return -args[0], nil
+	case "abs":
+		if args[0] < 0 {
+			return -args[0], nil
+		}
+		return args[0], nil
+	case "sqr":
+		return args[0] * args[0], nil
+	case "not":
+		return boolToInt(args[0] == 0), nil
+	case "sub":
+		return args[0] - args[1], nil
+	case "div", "mod":
+		if args[1] == 0 {
+			return 0, fmt.Errorf("%s by zero", op)
+		}
+		if op == "div" {
+			return args[0] / args[1], nil
+		}
+		return args[0] % args[1], nil
+	case "pow":
+		res := 1
+		for i := 0; i < args[1]; i++ {
+			res *= args[0]
+		}
+		return res, nil
+	case "dist":
+		if args[0] > args[1] {
+			return args[0] - args[1], nil
+		}
+		return args[1] - args[0], nil
+	case "lt":
+		return boolToInt(args[0] < args[1]), nil
+	case "le":
+		return boolToInt(args[0] <= args[1]), nil
+	case "gt":
+		return boolToInt(args[0] > args[1]), nil
+	case "ge":
+		return boolToInt(args[0] >= args[1]), nil
+	case "ne":
+		return boolToInt(args[0] != args[1]), nil
+	case "imp":
+		return boolToInt(args[0] == 0 || args[1] != 0), nil
+	}
+
+	// n-ary operators
+	res := args[0]
+	switch op {
+	case "add":
+		for _, v := range args[1:] {
+			res += v
+		}
+	case "mul":
+		for _, v := range args[1:] {
+			res *= v
+		}
+	case "min":
+		for _, v := range args[1:] {
+			if v < res {
+				res = v
+			}
+		}
+	case "max":
+		for _, v := range args[1:] {
+			if v > res {
+				res = v
+			}
+		}
+	case "and", "or", "xor":
+		count := 0
+		for _, v := range args {
+			if v != 0 {
+				count++
+			}
+		}
+		res = boolToInt((op == "and" && count == len(args)) || (op == "or" && count > 0) || (op == "xor" && count%2 == 1))
+	case "eq", "iff":
+		res = 1
+		for _, v := range args[1:] {
+			if (op == "eq" && v != args[0]) || (op == "iff" && (v != 0) != (args[0] != 0)) {
+				res = 0
+			}
+		}
+	default:
+		return 0, fmt.Errorf("%s not implemented", op)
+	}
+	return res, nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
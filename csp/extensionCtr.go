@@ -1,6 +1,10 @@
 package csp
 
-import "strings"
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
 
 // extensionCtr represents an extensional constraint in XCSP
 type extensionCtr struct {
@@ -35,6 +39,74 @@ func (c *extensionCtr) ToXCSP() []string {
 	return out
 }
 
+// NewExtensionCtr creates a positive extensional constraint
+func NewExtensionCtr(name string, vars []string, tuples [][]int) Constraint {
+	var sb strings.Builder
+	if len(vars) == 1 {
+		vals := make([]int, len(tuples))
+		for i, tup := range tuples {
+			vals[i] = tup[0]
+		}
+		sb.WriteString(FormatDomain(vals))
+	} else {
+		for _, tup := range tuples {
+			sb.WriteByte('(')
+			for i, v := range tup {
+				if i > 0 {
+					sb.WriteByte(',')
+				}
+				sb.WriteString(strconv.Itoa(v))
+			}
+			sb.WriteByte(')')
+		}
+	}
+	return &extensionCtr{CName: name, Vars: strings.Join(vars, " "), CType: "supports", Tuples: sb.String()}
+}
+
+var tupleRegex = regexp.MustCompile(`\(([^)]*)\)`)
+
+// ExtensionTable returns the scope and the allowed tuples of a positive
+// extensional constraint. It fails for other constraints and for tables
+// with stars.
+func ExtensionTable(c Constraint) ([]string, [][]int, bool) {
+	ext, ok := c.(*extensionCtr)
+	if !ok || ext.CType != "supports" || strings.Contains(ext.Tuples, "*") {
+		return nil, nil, false
+	}
+	vars := ext.Variables()
+	seen := make(map[string]bool)
+	for _, v := range vars {
+		if seen[v] {
+			return nil, nil, false
+		}
+		seen[v] = true
+	}
+
+	var tuples [][]int
+	if len(vars) == 1 {
+		for _, v := range DomainValues(ext.Tuples) {
+			tuples = append(tuples, []int{v})
+		}
+		return vars, tuples, true
+	}
+	for _, m := range tupleRegex.FindAllStringSubmatch(ext.Tuples, -1) {
+		vals := strings.Split(m[1], ",")
+		if len(vals) != len(vars) {
+			return nil, nil, false
+		}
+		tup := make([]int, len(vals))
+		for i, s := range vals {
+			v, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				return nil, nil, false
+			}
+			tup[i] = v
+		}
+		tuples = append(tuples, tup)
+	}
+	return vars, tuples, true
+}
+
 // AddVariable to this contraint scope
 /*func (c *ExtensionCtr) AddVariable(v string) {
 	c.Vars = append(c.Vars, v)
@@ -0,0 +1,155 @@
+package csp
+
+// FilterLimit bounds the size of the domains of unary intensions and the
+// number of tuples of the tables used by FilterDomains
+var FilterLimit = 10000
+
+// domainFilter keeps the values of the variables that are still supported.
+// Variables without restricted values keep their original domain.
+type domainFilter struct {
+	doms       map[string]string
+	orig       map[string]Domain
+	restricted map[string]map[int]bool
+}
+
+func (f *domainFilter) contains(v string, x int) bool {
+	if r, ok := f.restricted[v]; ok {
+		return r[x]
+	}
+	d, ok := f.orig[v]
+	if !ok {
+		d = ParseDomain(f.doms[v])
+		f.orig[v] = d
+	}
+	return d.Contains(x)
+}
+
+func (f *domainFilter) size(v string) int {
+	if r, ok := f.restricted[v]; ok {
+		return len(r)
+	}
+	return DomainSize(f.doms[v])
+}
+
+// restrict the values of v to the supported ones, and tell whether some value was removed
+func (f *domainFilter) restrict(v string, supported map[int]bool) bool {
+	vals := make(map[int]bool)
+	for x := range supported {
+		if f.contains(v, x) {
+			vals[x] = true
+		}
+	}
+	changed := len(vals) < f.size(v)
+	f.restricted[v] = vals
+	return changed
+}
+
+// FilterDomains removes from the domains the values without support in some
+// cheap constraint, i.e., unary intensions on domains with at most FilterLimit
+// values and positive tables with at most FilterLimit tuples, until a fixpoint.
+// It returns the new domains, and false if some domain becomes empty.
+func FilterDomains(ctrs map[string]Constraint, doms map[string]string) (map[string]string, bool) {
+	f := &domainFilter{doms: doms, orig: make(map[string]Domain), restricted: make(map[string]map[int]bool)}
+	type table struct {
+		vars   []string
+		tuples [][]int
+	}
+	var tables []table
+	for _, c := range ctrs {
+		if vars, tuples, ok := ExtensionTable(c); ok {
+			if len(tuples) <= FilterLimit {
+				tables = append(tables, table{vars, tuples})
+			}
+			continue
+		}
+		if v, supported, ok := unarySupports(c, doms); ok {
+			f.restrict(v, supported)
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, t := range tables {
+			supported := make([]map[int]bool, len(t.vars))
+			for i := range supported {
+				supported[i] = make(map[int]bool)
+			}
+			for _, tup := range t.tuples {
+				valid := true
+				for i, x := range tup {
+					if !f.contains(t.vars[i], x) {
+						valid = false
+						break
+					}
+				}
+				if valid {
+					for i, x := range tup {
+						supported[i][x] = true
+					}
+				}
+			}
+			for i, v := range t.vars {
+				if f.restrict(v, supported[i]) {
+					changed = true
+				}
+			}
+		}
+	}
+
+	res := make(map[string]string, len(doms))
+	for v, dom := range doms {
+		res[v] = dom
+	}
+	for v, vals := range f.restricted {
+		if len(vals) == 0 {
+			return nil, false
+		}
+		list := make([]int, 0, len(vals))
+		for x := range vals {
+			list = append(list, x)
+		}
+		res[v] = FormatDomain(list)
+	}
+	return res, true
+}
+
+// unarySupports evaluates an intensional constraint on a single variable
+// over its domain, if it has at most FilterLimit values. Constant
+// constraints and variables without a domain are skipped.
+func unarySupports(c Constraint, doms map[string]string) (string, map[int]bool, bool) {
+	prim, ok := c.(*primitiveCtr)
+	if !ok {
+		return "", nil, false
+	}
+	vars := prim.Variables()
+	if len(vars) == 0 {
+		return "", nil, false
+	}
+	v := vars[0]
+	if _, ok := doms[v]; !ok {
+		return "", nil, false
+	}
+	for _, u := range vars {
+		if u != v {
+			return "", nil, false
+		}
+	}
+	if DomainSize(doms[v]) > FilterLimit {
+		return "", nil, false
+	}
+	e, err := ParseExpr(prim.Function)
+	if err != nil {
+		return "", nil, false
+	}
+	supported := make(map[int]bool)
+	for _, x := range DomainValues(doms[v]) {
+		res, err := e.Eval(map[string]int{v: x})
+		if err != nil {
+			return "", nil, false
+		}
+		if res != 0 {
+			supported[x] = true
+		}
+	}
+	return v, supported, true
+}
@@ -0,0 +1,74 @@
+package csp
+
+import "testing"
+
+func TestEvalExpr(t *testing.T) {
+	vals := map[string]int{"x": 3, "y[1]": -2}
+	tests := map[string]int{
+		"gt(add(x,1),3)":                1,
+		"eq(x, 3, sub(1, y[1]))":        1,
+		"le(mul(x,y[1]),-7)":            0,
+		"in(x,set(1,3,5))":              1,
+		"notin(y[1],set())":             1,
+		"if(ne(x,3),10,dist(x,y[1]))":   5,
+		"and(ge(x,0),or(lt(y[1],0),0))": 1,
+		"mod(x,2)":                      1,
+	}
+	for s, exp := range tests {
+		e, err := ParseExpr(s)
+		if err != nil {
+			t.Errorf("%s: %v", s, err)
+			continue
+		}
+		if res, err := e.Eval(vals); err != nil || res != exp {
+			t.Errorf("%s = %v (%v), expected %v", s, res, err, exp)
+		}
+	}
+	for _, s := range []string{"gt(x,", "foo(x)", "add(x,z)"} {
+		if e, err := ParseExpr(s); err == nil {
+			if _, err := e.Eval(vals); err == nil {
+				t.Errorf("%s evaluated without errors", s)
+			}
+		}
+	}
+}
+
+func TestFilterDomains(t *testing.T) {
+	ctrs := map[string]Constraint{
+		"c1": &primitiveCtr{CName: "c1", Vars: "x", Function: "ge(x,2)"},
+		"c2": &extensionCtr{CName: "c2", Vars: "x y", CType: "supports", Tuples: "(0,0)(2,1)(3,5)(4,2)"},
+		"c3": &extensionCtr{CName: "c3", Vars: "y", CType: "supports", Tuples: "1..3"},
+		"c4": &primitiveCtr{CName: "c4", Vars: "y z", Function: "ne(y,z)"},
+	}
+	doms := map[string]string{"x": "0..3", "y": "0..9", "z": "0..9"}
+	res, ok := FilterDomains(ctrs, doms)
+	if !ok {
+		t.Fatal("domains wiped out")
+	}
+	expected := map[string]string{"x": "2", "y": "1", "z": "0..9"}
+	for v, dom := range expected {
+		if res[v] != dom {
+			t.Errorf("domain of %v = %v, expected %v", v, res[v], dom)
+		}
+	}
+	if doms["x"] != "0..3" {
+		t.Error("original domains changed")
+	}
+
+	ctrs["c6"] = &primitiveCtr{CName: "c6", Function: "1", strVars: []string{}}
+	ctrs["c7"] = &primitiveCtr{CName: "c7", Vars: "", Function: "1"}
+	if res, ok := FilterDomains(ctrs, doms); !ok || res["y"] != "1" {
+		t.Errorf("constant constraints changed the domains: %v", res)
+	}
+
+	ctrs["c5"] = &primitiveCtr{CName: "c5", Vars: "y", Function: "gt(y,1)"}
+	if _, ok := FilterDomains(ctrs, doms); ok {
+		t.Error("domain of y not wiped out")
+	}
+}
+
+func TestFormatDomain(t *testing.T) {
+	if res := FormatDomain([]int{7, 1, 2, 3, -1, 9, 8}); res != "-1 1..3 7..9" {
+		t.Errorf("res= %v; want -1 1..3 7..9", res)
+	}
+}
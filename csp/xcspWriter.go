@@ -2,6 +2,7 @@ package csp
 
 import (
 	"bufio"
+	"io"
 	"os"
 	"regexp"
 	"sort"
@@ -9,10 +10,26 @@ import (
 	"strings"
 )
 
-// TODO maybe instead of writing to file, I could just return strings (for in-memory reasons)
+// CreateXCSPInstance from given constraints, writing it to out
+func CreateXCSPInstance(constraints []Constraint, variables map[string]string, out io.Writer) {
+	w := bufio.NewWriter(out)
+	_, err := w.WriteString("<instance format=\"XCSP3\" type=\"CSP\">\n")
+	if err != nil {
+		panic(err)
+	}
+	writeVariables(w, variables)
+	writeConstraints(w, constraints)
+	_, err = w.WriteString("</instance>\n")
+	if err != nil {
+		panic(err)
+	}
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
+}
 
-// CreateXCSPInstance from given constraints
-func CreateXCSPInstance(constraints []Constraint, variables map[string]string, outFile string) {
+// CreateXCSPFile from given constraints
+func CreateXCSPFile(constraints []Constraint, variables map[string]string, outFile string) {
 	file, err := os.Create(outFile)
 	if err != nil {
 		panic(err)
@@ -22,19 +39,7 @@ func CreateXCSPInstance(constraints []Constraint, variables map[string]string, o
 			panic(err)
 		}
 	}()
-
-	w := bufio.NewWriter(file)
-	_, err = w.WriteString("<instance format=\"XCSP3\" type=\"CSP\">\n")
-	if err != nil {
-		panic(err)
-	}
-	writeVariables(w, variables)
-	writeConstraints(w, constraints)
-	_, err = w.WriteString("</instance>\n")
-	if err != nil {
-		panic(err)
-	}
-	w.Flush()
+	CreateXCSPInstance(constraints, variables, file)
 }
 
 func writeVariables(w *bufio.Writer, variables map[string]string) {
@@ -61,3 +61,26 @@ func TestMakeVarListCompressed2(t *testing.T) {
 		}
 	}
 }
+
+func TestCanonicalForm(t *testing.T) {
+	inst1 := "<instance format=\"XCSP3\" type=\"CSP\">\n\t<variables>\n\t\t<var id=\"a\"> 0..3 </var>\n\t\t<var id=\"b\"> 0..5 </var>\n\t</variables>\n" +
+		"\t<constraints>\n\t\t<intension> lt(b,a) </intension>\n\t</constraints>\n</instance>\n"
+	vars1 := map[string]string{"a": "0..3", "b": "0..5"}
+	inst2 := "<instance format=\"XCSP3\" type=\"CSP\">\n\t<variables>\n\t\t<var id=\"xL1J\"> 0..5 </var>\n\t\t<var id=\"z\"> 0..3 </var>\n\t</variables>\n" +
+		"\t<constraints>\n\t\t<intension> lt(xL1J,z) </intension>\n\t</constraints>\n</instance>\n"
+	vars2 := map[string]string{"xL1J": "0..5", "z": "0..3"}
+
+	canon1, ren1 := CanonicalForm(inst1, vars1)
+	canon2, ren2 := CanonicalForm(inst2, vars2)
+	if canon1 != canon2 {
+		t.Errorf("different canonical forms:\n%s\n%s", canon1, canon2)
+	}
+	if ren1["b"] != "V0" || ren1["a"] != "V1" || ren2["xL1J"] != "V0" || ren2["z"] != "V1" {
+		t.Errorf("bad renamings %v, %v", ren1, ren2)
+	}
+
+	vars2["z"] = "0..4"
+	if canon3, _ := CanonicalForm(inst2, vars2); canon3 == canon1 {
+		t.Error("same canonical form with different domains")
+	}
+}
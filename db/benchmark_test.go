@@ -0,0 +1,68 @@
+package db
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// benchRelations creates two relations sorted on their common attribute A
+func benchRelations(n int) (Relation, Relation) {
+	rnd := rand.New(rand.NewSource(42))
+	l := NewRelation([]string{"A", "B"})
+	r := NewRelation([]string{"A", "C"})
+	for i := 0; i < n; i++ {
+		l.AddTuple([]int{i, rnd.Intn(n)})
+		r.AddTuple([]int{2 * i, rnd.Intn(n)})
+	}
+	return l, r
+}
+
+func benchmarkSemijoin(b *testing.B, semijoin func(Relation, Relation)) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		l, r := benchRelations(2000)
+		b.StartTimer()
+		semijoin(l, r)
+	}
+}
+
+func BenchmarkSemijoinNested(b *testing.B) {
+	benchmarkSemijoin(b, func(l, r Relation) { nestedSemijoin(l, r) })
+}
+
+func BenchmarkSemijoinHash(b *testing.B) {
+	benchmarkSemijoin(b, func(l, r Relation) {
+		lCols, rCols := splitJoinIdx(commonAttrs(l, r))
+		removeMatching(l, lCols, r, rCols, false)
+	})
+}
+
+func BenchmarkSemijoinMerge(b *testing.B) {
+	benchmarkSemijoin(b, func(l, r Relation) {
+		lCols, rCols := splitJoinIdx(commonAttrs(l, r))
+		mergeSemijoin(l, lCols, r, rCols, 1)
+	})
+}
+
+func BenchmarkJoinNested(b *testing.B) {
+	l, r := benchRelations(2000)
+	for i := 0; i < b.N; i++ {
+		nestedJoin(l, r)
+	}
+}
+
+func BenchmarkJoinHash(b *testing.B) {
+	l, r := benchRelations(2000)
+	lCols, rCols := splitJoinIdx(commonAttrs(l, r))
+	for i := 0; i < b.N; i++ {
+		hashJoin(l, lCols, r, rCols)
+	}
+}
+
+func BenchmarkJoinMerge(b *testing.B) {
+	l, r := benchRelations(2000)
+	lCols, rCols := splitJoinIdx(commonAttrs(l, r))
+	for i := 0; i < b.N; i++ {
+		mergeJoin(l, lCols, r, rCols, 1)
+	}
+}
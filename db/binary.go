@@ -0,0 +1,303 @@
+package db
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// Binary format of a relation:
+//
+//	header: "CRL" version | #attrs | (len | name)* | #tuples (uint64) | crc32
+//	blocks: #rows | #bytes | values (zigzag varints) | crc32
+//
+// All counts are uvarints unless stated otherwise, and checksums are
+// little-endian CRC-32 (IEEE) of the preceding bytes of the header or block.
+
+const binaryVersion = 1
+const blockRows = 1024
+
+// maxNameLen bounds the length of attribute names, so that corrupted
+// headers are detected before allocating their names
+const maxNameLen = 1 << 12
+
+var binaryMagic = []byte("CRL")
+
+// ErrChecksum is returned when a relation file is corrupted
+var ErrChecksum = errors.New("relation checksum mismatch")
+
+// WriteRelation writes the live tuples of a relation in binary format
+func WriteRelation(out io.Writer, r Relation) error {
+	w := bufio.NewWriter(out)
+	var hdr bytes.Buffer
+	hdr.Write(binaryMagic)
+	hdr.WriteByte(binaryVersion)
+	writeUvarint(&hdr, uint64(len(r.Attributes())))
+	for _, a := range r.Attributes() {
+		writeUvarint(&hdr, uint64(len(a)))
+		hdr.WriteString(a)
+	}
+	var count [8]byte
+	binary.LittleEndian.PutUint64(count[:], uint64(r.Size()))
+	hdr.Write(count[:])
+	if err := writeChecked(w, hdr.Bytes()); err != nil {
+		return err
+	}
+
+	var block bytes.Buffer
+	rows := 0
+	flushBlock := func() error {
+		var prefix bytes.Buffer
+		writeUvarint(&prefix, uint64(rows))
+		writeUvarint(&prefix, uint64(block.Len()))
+		if _, err := w.Write(prefix.Bytes()); err != nil {
+			return err
+		}
+		if err := writeChecked(w, block.Bytes()); err != nil {
+			return err
+		}
+		block.Reset()
+		rows = 0
+		return nil
+	}
+	var buf [binary.MaxVarintLen64]byte
+	for i := 0; i < r.Len(); i++ {
+		if !r.Live(i) {
+			continue
+		}
+		for col := range r.Attributes() {
+			n := binary.PutVarint(buf[:], int64(r.Value(i, col)))
+			block.Write(buf[:n])
+		}
+		rows++
+		if rows == blockRows {
+			if err := flushBlock(); err != nil {
+				return err
+			}
+		}
+	}
+	if rows > 0 {
+		if err := flushBlock(); err != nil {
+			return err
+		}
+	}
+	return w.Flush()
+}
+
+func writeUvarint(b *bytes.Buffer, v uint64) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], v)
+	b.Write(buf[:n])
+}
+
+func writeChecked(w io.Writer, data []byte) error {
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	var sum [4]byte
+	binary.LittleEndian.PutUint32(sum[:], crc32.ChecksumIEEE(data))
+	_, err := w.Write(sum[:])
+	return err
+}
+
+// RelationReader reads a relation in binary format one tuple at a time
+type RelationReader struct {
+	r     *bufio.Reader
+	attrs []string
+	count uint64
+	read  uint64
+
+	block     []byte
+	blockLeft uint64
+}
+
+// NewRelationReader reads the header of a relation in binary format
+func NewRelationReader(in io.Reader) (*RelationReader, error) {
+	rr := &RelationReader{r: bufio.NewReader(in)}
+	var hdr bytes.Buffer
+	tee := io.TeeReader(rr.r, &hdr)
+
+	magic := make([]byte, len(binaryMagic)+1)
+	if _, err := io.ReadFull(tee, magic); err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(magic[:len(binaryMagic)], binaryMagic) {
+		return nil, errors.New("not a relation file")
+	}
+	if magic[len(binaryMagic)] != binaryVersion {
+		return nil, fmt.Errorf("unsupported relation version %v", magic[len(binaryMagic)])
+	}
+
+	numAttrs, err := binary.ReadUvarint(byteReader{tee})
+	if err != nil {
+		return nil, err
+	}
+	for i := uint64(0); i < numAttrs; i++ {
+		l, err := binary.ReadUvarint(byteReader{tee})
+		if err != nil {
+			return nil, err
+		}
+		if l > maxNameLen {
+			return nil, fmt.Errorf("attribute name of %v bytes in relation header", l)
+		}
+		name := make([]byte, l)
+		if _, err := io.ReadFull(tee, name); err != nil {
+			return nil, err
+		}
+		rr.attrs = append(rr.attrs, string(name))
+	}
+	var count [8]byte
+	if _, err := io.ReadFull(tee, count[:]); err != nil {
+		return nil, err
+	}
+	rr.count = binary.LittleEndian.Uint64(count[:])
+	if err := rr.checkSum(hdr.Bytes()); err != nil {
+		return nil, err
+	}
+	return rr, nil
+}
+
+// byteReader reads one byte at a time from a reader
+type byteReader struct {
+	io.Reader
+}
+
+func (br byteReader) ReadByte() (byte, error) {
+	var b [1]byte
+	_, err := io.ReadFull(br.Reader, b[:])
+	return b[0], err
+}
+
+func (rr *RelationReader) checkSum(data []byte) error {
+	var sum [4]byte
+	if _, err := io.ReadFull(rr.r, sum[:]); err != nil {
+		return err
+	}
+	if binary.LittleEndian.Uint32(sum[:]) != crc32.ChecksumIEEE(data) {
+		return ErrChecksum
+	}
+	return nil
+}
+
+// Attributes of the relation being read
+func (rr *RelationReader) Attributes() []string {
+	return rr.attrs
+}
+
+// Count of the tuples of the relation being read
+func (rr *RelationReader) Count() uint64 {
+	return rr.count
+}
+
+// Next tuple of the relation, or io.EOF when all of them have been read
+func (rr *RelationReader) Next() (Tuple, error) {
+	if rr.read == rr.count {
+		return nil, io.EOF
+	}
+	if rr.blockLeft == 0 {
+		if err := rr.nextBlock(); err != nil {
+			return nil, err
+		}
+	}
+	tup := make(Tuple, len(rr.attrs))
+	for i := range tup {
+		v, n := binary.Varint(rr.block)
+		if n <= 0 {
+			return nil, errors.New("bad value in relation block")
+		}
+		tup[i] = int(v)
+		rr.block = rr.block[n:]
+	}
+	rr.blockLeft--
+	rr.read++
+	return tup, nil
+}
+
+func (rr *RelationReader) nextBlock() error {
+	rows, err := binary.ReadUvarint(rr.r)
+	if err != nil {
+		return unexpected(err)
+	}
+	size, err := binary.ReadUvarint(rr.r)
+	if err != nil {
+		return unexpected(err)
+	}
+	// checked before reading the block, whose checksum comes after it
+	if rows > blockRows || size > uint64(blockRows*len(rr.attrs)*binary.MaxVarintLen64) {
+		return fmt.Errorf("relation block of %v rows and %v bytes", rows, size)
+	}
+	block := make([]byte, size)
+	if _, err := io.ReadFull(rr.r, block); err != nil {
+		return unexpected(err)
+	}
+	if err := rr.checkSum(block); err != nil {
+		return unexpected(err)
+	}
+	rr.block = block
+	rr.blockLeft = rows
+	return nil
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// ReadRelation in binary format
+func ReadRelation(in io.Reader) (Relation, error) {
+	rr, err := NewRelationReader(in)
+	if err != nil {
+		return nil, err
+	}
+	rel := NewRelation(rr.Attributes())
+	for {
+		tup, err := rr.Next()
+		if err == io.EOF {
+			return rel, nil
+		} else if err != nil {
+			return nil, err
+		}
+		rel.AddTuple(tup)
+	}
+}
+
+// RelToBinFile creates a file containing the given relation in binary format
+func RelToBinFile(filename string, r Relation) {
+	file, err := os.Create(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			panic(err)
+		}
+	}()
+	if err := WriteRelation(file, r); err != nil {
+		panic(err)
+	}
+}
+
+// RelFromBinFile reads a relation from a file in binary format
+func RelFromBinFile(filename string) Relation {
+	file, err := os.Open(filename)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			panic(err)
+		}
+	}()
+	rel, err := ReadRelation(file)
+	if err != nil {
+		panic(fmt.Sprintf("%s: %v", filename, err))
+	}
+	return rel
+}
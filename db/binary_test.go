@@ -0,0 +1,89 @@
+package db
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func TestBinaryRelation(t *testing.T) {
+	attrs := []string{"Y", "Z", "U"}
+	var tuples []Tuple
+	for i := 0; i < 2*blockRows+7; i++ {
+		tuples = append(tuples, Tuple{i, -i, i * 1000003})
+	}
+	r := fill(NewRelation(attrs), tuples)
+	r.RemoveTuples([]int{0, 5})
+
+	var buf bytes.Buffer
+	if err := WriteRelation(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	rr, err := NewRelationReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rr.Attributes()) != 3 || rr.Attributes()[2] != "U" || rr.Count() != uint64(r.Size()) {
+		t.Fatalf("bad header: attrs=%v count=%v", rr.Attributes(), rr.Count())
+	}
+	n := 0
+	for {
+		tup, err := rr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		n++
+		if tup[1] != -tup[0] {
+			t.Fatalf("bad tuple %v", tup)
+		}
+	}
+	if n != r.Size() {
+		t.Errorf("read %v tuples, expected %v", n, r.Size())
+	}
+
+	path := filepath.Join(t.TempDir(), "sub1.tab")
+	RelToBinFile(path, r)
+	if back := RelFromBinFile(path); !relEquals(r, back) {
+		t.Errorf("relation changed after writing and reading it back")
+	}
+}
+
+func TestBinaryRelationCorrupted(t *testing.T) {
+	r := fill(NewRelation([]string{"A", "B"}), []Tuple{{1, 2}, {3, 4}})
+	var buf bytes.Buffer
+	if err := WriteRelation(&buf, r); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	bad := append([]byte(nil), data...)
+	bad[len(bad)-6]++
+	if _, err := ReadRelation(bytes.NewReader(bad)); err != ErrChecksum {
+		t.Errorf("corrupted block: err=%v, expected %v", err, ErrChecksum)
+	}
+	bad = append([]byte(nil), data...)
+	bad[5]++
+	if _, err := ReadRelation(bytes.NewReader(bad)); err == nil {
+		t.Error("corrupted header not detected")
+	}
+	if _, err := ReadRelation(bytes.NewReader(data[:len(data)-3])); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated file: err=%v, expected %v", err, io.ErrUnexpectedEOF)
+	}
+
+	// huge lengths are rejected before allocating anything
+	var huge [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(huge[:], 1<<40)
+	bad = append(append(append([]byte(nil), data[:5]...), huge[:n]...), data[6:]...)
+	if _, err := ReadRelation(bytes.NewReader(bad)); err == nil || err == io.ErrUnexpectedEOF {
+		t.Errorf("huge attribute name: err=%v", err)
+	}
+	hdrLen := 4 + 1 + 2*2 + 8 + 4
+	bad = append(append(append([]byte(nil), data[:hdrLen+1]...), huge[:n]...), data[hdrLen+2:]...)
+	if _, err := ReadRelation(bytes.NewReader(bad)); err == nil || err == io.ErrUnexpectedEOF {
+		t.Errorf("huge block: err=%v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package db
+
+import "fmt"
+
+// CompactRatio is the fraction of deleted tuples that triggers the compaction of a relation
+var CompactRatio = 0.5
+
+// deletions marks the deleted tuples of a relation in a bitmap
+type deletions struct {
+	bits    []uint64
+	numDead int
+}
+
+// Live tells whether the i-th tuple has not been deleted
+func (d *deletions) Live(i int) bool {
+	w := i / 64
+	return w >= len(d.bits) || d.bits[w]&(1<<(uint(i)%64)) == 0
+}
+
+func (d *deletions) markDead(idx []int, size int) (bool, error) {
+	res := false
+	for _, i := range idx {
+		if i < 0 || i >= size {
+			return res, fmt.Errorf("index %v out of range [0, %v)", i, size)
+		}
+		if !d.Live(i) {
+			continue
+		}
+		w := i / 64
+		for w >= len(d.bits) {
+			d.bits = append(d.bits, 0)
+		}
+		d.bits[w] |= 1 << (uint(i) % 64)
+		d.numDead++
+		res = true
+	}
+	return res, nil
+}
+
+func (d *deletions) needsCompaction(size int) bool {
+	return d.numDead > 0 && float64(d.numDead) >= CompactRatio*float64(size)
+}
+
+func (d *deletions) reset() {
+	d.bits = nil
+	d.numDead = 0
+}
@@ -0,0 +1,196 @@
+package db
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+)
+
+// indexRows is the number of entries a diskIndex keeps in memory
+var indexRows = 1 << 16
+
+// maxRuns is the number of runs after which a diskIndex merges them
+var maxRuns = 8
+
+// entrySize is the size of a (hash, position) pair in a run
+const entrySize = 16
+
+// diskIndex is a tupleIndex that keeps at most indexRows entries in memory
+// and moves the others to files of (hash, position) pairs sorted by hash
+type diskIndex struct {
+	dir  string
+	mem  tupleIndex
+	size int
+	runs []run
+}
+
+// run is a file of entries sorted by hash
+type run struct {
+	file *os.File
+	n    int
+}
+
+func newDiskIndex(dir string) *diskIndex {
+	return &diskIndex{dir: dir, mem: make(tupleIndex)}
+}
+
+func (idx *diskIndex) add(h uint64, i int) {
+	idx.mem[h] = append(idx.mem[h], i)
+	idx.size++
+	if idx.size >= indexRows {
+		idx.flush()
+	}
+}
+
+// find the position of a live tuple of r equal to vals
+func (idx *diskIndex) find(r Relation, h uint64, vals []int) (int, bool) {
+	if i, found := idx.mem.find(r, h, vals); found {
+		return i, true
+	}
+	var entry [entrySize]byte
+	for _, run := range idx.runs {
+		for k := run.search(h); k < run.n; k++ {
+			run.read(k, entry[:])
+			if binary.LittleEndian.Uint64(entry[:8]) != h {
+				break
+			}
+			i := int(binary.LittleEndian.Uint64(entry[8:]))
+			if r.Live(i) && sameRow(r, i, vals) {
+				return i, true
+			}
+		}
+	}
+	return -1, false
+}
+
+// search the first entry of a run whose hash is not smaller than h
+func (r run) search(h uint64) int {
+	var buf [8]byte
+	return sort.Search(r.n, func(k int) bool {
+		r.read(k, buf[:])
+		return binary.LittleEndian.Uint64(buf[:]) >= h
+	})
+}
+
+// read the beginning of the k-th entry of a run into buf
+func (r run) read(k int, buf []byte) {
+	if _, err := r.file.ReadAt(buf, int64(k)*entrySize); err != nil {
+		panic(err)
+	}
+}
+
+// flush the entries in memory to a new run, merging the runs if there are too many
+func (idx *diskIndex) flush() {
+	hashes := make([]uint64, 0, len(idx.mem))
+	for h := range idx.mem {
+		hashes = append(hashes, h)
+	}
+	sort.Slice(hashes, func(a, b int) bool { return hashes[a] < hashes[b] })
+
+	file, w := idx.newRun()
+	for _, h := range hashes {
+		for _, i := range idx.mem[h] {
+			writeEntry(w, h, i)
+		}
+	}
+	flushRun(w)
+	idx.runs = append(idx.runs, run{file, idx.size})
+	idx.mem = make(tupleIndex)
+	idx.size = 0
+
+	if len(idx.runs) > maxRuns {
+		idx.merge()
+	}
+}
+
+// merge all the runs into one
+func (idx *diskIndex) merge() {
+	readers := make([]*bufio.Reader, len(idx.runs))
+	heads := make([][entrySize]byte, len(idx.runs))
+	live := make([]bool, len(idx.runs))
+	total := 0
+	for k, r := range idx.runs {
+		readers[k] = bufio.NewReader(io.NewSectionReader(r.file, 0, int64(r.n)*entrySize))
+		live[k] = readEntry(readers[k], &heads[k])
+		total += r.n
+	}
+
+	file, w := idx.newRun()
+	for {
+		next := -1
+		for k := range heads {
+			if live[k] && (next < 0 || binary.LittleEndian.Uint64(heads[k][:8]) < binary.LittleEndian.Uint64(heads[next][:8])) {
+				next = k
+			}
+		}
+		if next < 0 {
+			break
+		}
+		if _, err := w.Write(heads[next][:]); err != nil {
+			panic(err)
+		}
+		live[next] = readEntry(readers[next], &heads[next])
+	}
+	flushRun(w)
+
+	if err := idx.close(); err != nil {
+		panic(err)
+	}
+	idx.runs = []run{{file, total}}
+}
+
+func (idx *diskIndex) newRun() (*os.File, *bufio.Writer) {
+	file, err := ioutil.TempFile(idx.dir, "idx*.bin")
+	if err != nil {
+		panic(err)
+	}
+	return file, bufio.NewWriter(file)
+}
+
+func flushRun(w *bufio.Writer) {
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
+}
+
+func writeEntry(w *bufio.Writer, h uint64, i int) {
+	var entry [entrySize]byte
+	binary.LittleEndian.PutUint64(entry[:8], h)
+	binary.LittleEndian.PutUint64(entry[8:], uint64(i))
+	if _, err := w.Write(entry[:]); err != nil {
+		panic(err)
+	}
+}
+
+func readEntry(r *bufio.Reader, entry *[entrySize]byte) bool {
+	if _, err := io.ReadFull(r, entry[:]); err == io.EOF {
+		return false
+	} else if err != nil {
+		panic(err)
+	}
+	return true
+}
+
+// close removes the runs of this index
+func (idx *diskIndex) close() error {
+	var res error
+	for _, r := range idx.runs {
+		if err := closeTemp(r.file); err != nil && res == nil {
+			res = err
+		}
+	}
+	idx.runs = nil
+	return res
+}
+
+// closeTemp closes and removes a temporary file
+func closeTemp(file *os.File) error {
+	err := file.Close()
+	if rmErr := os.Remove(file.Name()); rmErr != nil && !os.IsNotExist(rmErr) && err == nil {
+		err = rmErr
+	}
+	return err
+}
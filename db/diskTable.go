@@ -0,0 +1,275 @@
+package db
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sync"
+)
+
+const pageRows = 4096
+
+// diskTable stores its tuples in a binary file, one fixed-size row after
+// the other, and reads them back a page at a time
+type diskTable struct {
+	attrs   []string
+	attrPos map[string]int
+	rows    int
+
+	file  *os.File
+	w     *bufio.Writer
+	dirty bool
+
+	mu        sync.Mutex
+	page      []byte
+	pageStart int
+	pageLen   int
+
+	deletions
+	sortedness
+	set *diskIndex
+}
+
+// newDiskTable creates an empty relation backed by a file in dir
+func newDiskTable(attrs []string, dir string) *diskTable {
+	file, err := ioutil.TempFile(dir, "rel*.bin")
+	if err != nil {
+		panic(err)
+	}
+	t := &diskTable{
+		attrs:      attrs,
+		attrPos:    makeAttrPos(attrs),
+		file:       file,
+		w:          bufio.NewWriter(file),
+		sortedness: newSortedness(len(attrs)),
+	}
+	runtime.SetFinalizer(t, func(t *diskTable) { t.close() })
+	return t
+}
+
+// close removes the files of this relation. Errors are returned instead of
+// panicking, since close also runs in the finalizer goroutine.
+func (t *diskTable) close() error {
+	err := closeTemp(t.file)
+	if t.set != nil {
+		if idxErr := t.set.close(); idxErr != nil && err == nil {
+			err = idxErr
+		}
+	}
+	return err
+}
+
+// free removes the files of this relation, which cannot be used anymore
+func (t *diskTable) free() {
+	runtime.SetFinalizer(t, nil)
+	if err := t.close(); err != nil {
+		panic(err)
+	}
+}
+
+func (t *diskTable) rowSize() int {
+	return 8 * len(t.attrs)
+}
+
+func (t *diskTable) Empty() bool {
+	return t.Size() == 0
+}
+
+func (t *diskTable) Attributes() []string {
+	return t.attrs
+}
+
+func (t *diskTable) Position(attr string) (pos int, ok bool) {
+	pos, ok = t.attrPos[attr]
+	return
+}
+
+// AddTuple to this relation. It returns the tuple and false if the
+// arity is wrong (nil) or if the tuple is a duplicate under set semantics.
+func (t *diskTable) AddTuple(vals []int) (Tuple, bool) {
+	if len(t.attrs) != len(vals) {
+		return nil, false
+	}
+	if t.set != nil {
+		h := hashTuple(vals)
+		if _, found := t.set.find(t, h, vals); found {
+			return vals, false
+		}
+		t.set.add(h, t.rows)
+	}
+	var buf [8]byte
+	for _, v := range vals {
+		binary.LittleEndian.PutUint64(buf[:], uint64(v))
+		if _, err := t.w.Write(buf[:]); err != nil {
+			panic(err)
+		}
+	}
+	t.rows++
+	t.dirty = true
+	t.track(vals)
+	return vals, true
+}
+
+// RemoveTuples marks the given tuples as deleted, and compacts the
+// relation when enough of its tuples are deleted
+func (t *diskTable) RemoveTuples(idx []int) (bool, error) {
+	res, err := t.markDead(idx, t.rows)
+	if err != nil {
+		return res, err
+	}
+	if t.needsCompaction(t.rows) {
+		t.Compact()
+	}
+	return res, nil
+}
+
+// Compact rewrites the file of this relation without the deleted tuples
+func (t *diskTable) Compact() {
+	if t.numDead == 0 {
+		return
+	}
+	newT := newDiskTable(t.attrs, filepath.Dir(t.file.Name()))
+	runtime.SetFinalizer(newT, nil)
+	for i := 0; i < t.rows; i++ {
+		if t.Live(i) {
+			newT.AddTuple(t.Tuple(i))
+		}
+	}
+	if err := closeTemp(t.file); err != nil {
+		panic(err)
+	}
+	t.file, t.w, t.rows, t.dirty = newT.file, newT.w, newT.rows, true
+	t.pageLen = 0
+	t.reset()
+	if t.set != nil {
+		t.dropIndex()
+		t.set = t.index()
+	}
+}
+
+func (t *diskTable) SetDistinct(on bool) {
+	t.dropIndex()
+	if !on {
+		return
+	}
+	idx, _ := t.removeDuplicates()
+	if t.numDead > 0 {
+		if err := idx.close(); err != nil {
+			panic(err)
+		}
+		t.Compact()
+		idx = t.index()
+	}
+	t.set = idx
+}
+
+// removeDuplicates marks as deleted the duplicates of the live tuples,
+// keeping their first occurrence. It returns an index of the other tuples.
+func (t *diskTable) removeDuplicates() (*diskIndex, bool) {
+	idx := newDiskIndex(filepath.Dir(t.file.Name()))
+	var tupToDel []int
+	for i := 0; i < t.rows; i++ {
+		if !t.Live(i) {
+			continue
+		}
+		tup := t.Tuple(i)
+		h := hashTuple(tup)
+		if _, found := idx.find(t, h, tup); found {
+			tupToDel = append(tupToDel, i)
+		} else {
+			idx.add(h, i)
+		}
+	}
+	res, err := t.markDead(tupToDel, t.rows)
+	if err != nil {
+		panic(err)
+	}
+	return idx, res
+}
+
+// index the live tuples of this relation
+func (t *diskTable) index() *diskIndex {
+	idx := newDiskIndex(filepath.Dir(t.file.Name()))
+	for i := 0; i < t.rows; i++ {
+		if t.Live(i) {
+			idx.add(hashRow(t, i), i)
+		}
+	}
+	return idx
+}
+
+func (t *diskTable) dropIndex() {
+	if t.set != nil {
+		if err := t.set.close(); err != nil {
+			panic(err)
+		}
+		t.set = nil
+	}
+}
+
+// Tuples loads in memory the tuples of this relation that have not been deleted
+func (t *diskTable) Tuples() []Tuple {
+	res := make([]Tuple, 0, t.Size())
+	for i := 0; i < t.rows; i++ {
+		if t.Live(i) {
+			res = append(res, t.Tuple(i))
+		}
+	}
+	return res
+}
+
+func (t *diskTable) Len() int {
+	return t.rows
+}
+
+func (t *diskTable) Size() int {
+	return t.rows - t.numDead
+}
+
+func (t *diskTable) Value(i int, col int) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	row := t.load(i)
+	return int(int64(binary.LittleEndian.Uint64(row[8*col:])))
+}
+
+func (t *diskTable) Tuple(i int) Tuple {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	row := t.load(i)
+	tup := make(Tuple, len(t.attrs))
+	for j := range tup {
+		tup[j] = int(int64(binary.LittleEndian.Uint64(row[8*j:])))
+	}
+	return tup
+}
+
+// load the page containing the i-th tuple and return its bytes
+func (t *diskTable) load(i int) []byte {
+	if t.dirty {
+		if err := t.w.Flush(); err != nil {
+			panic(err)
+		}
+		t.dirty = false
+		t.pageLen = 0
+	}
+	if i < t.pageStart || i >= t.pageStart+t.pageLen {
+		size := t.rowSize()
+		if t.page == nil {
+			t.page = make([]byte, pageRows*size)
+		}
+		t.pageStart = i - i%pageRows
+		n, err := t.file.ReadAt(t.page, int64(t.pageStart*size))
+		if err != nil && err != io.EOF {
+			panic(err)
+		}
+		t.pageLen = n / size
+	}
+	size := t.rowSize()
+	off := (i - t.pageStart) * size
+	return t.page[off : off+size]
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDiskTable(t *testing.T) {
+	attrs := []string{"Y", "Z", "U"}
+	tuples := []Tuple{{3, 8, 9}, {9, 3, 8}, {8, 3, 8}, {3, 8, 4}, {3, 8, 3}, {8, 9, 4}, {-9, 4, 7}}
+	row := fill(NewRelationOf(RowImpl, attrs), tuples)
+	disk := fill(newDiskTable(attrs, t.TempDir()), tuples)
+	if !relEquals(row, disk) {
+		t.Fatalf("disk=\n%s, expected\n%s", RelToString(disk), RelToString(row))
+	}
+
+	idx := []int{0, 2, 6}
+	row.RemoveTuples(idx)
+	disk.RemoveTuples(idx)
+	disk.AddTuple([]int{1, 2, 3})
+	row.AddTuple([]int{1, 2, 3})
+	if !relEquals(row, disk) {
+		t.Errorf("disk=\n%s, expected\n%s", RelToString(disk), RelToString(row))
+	}
+	disk.Compact()
+	if disk.Len() != 5 || !relEquals(row, disk) {
+		t.Errorf("disk=\n%s, expected\n%s", RelToString(disk), RelToString(row))
+	}
+}
+
+func TestSpillTable(t *testing.T) {
+	SetMemoryBudget(1, t.TempDir())
+	defer SetMemoryBudget(0, "")
+
+	r := NewRelation([]string{"A", "B"})
+	r.SetDistinct(true)
+	for i := 0; i < 2*checkEvery; i++ {
+		r.AddTuple([]int{i, -i})
+	}
+	if _, added := r.AddTuple([]int{5, -5}); added {
+		t.Error("duplicate added after spilling")
+	}
+	st, ok := r.(*spillTable)
+	if !ok || !st.spilled {
+		t.Fatal("relation not moved to disk")
+	}
+	if r.Size() != 2*checkEvery || r.Value(checkEvery+1, 1) != -(checkEvery+1) {
+		t.Errorf("Size() = %v, Value() = %v", r.Size(), r.Value(checkEvery+1, 1))
+	}
+}
+
+func TestDiskDistinct(t *testing.T) {
+	defer func(rows, runs int) { indexRows, maxRuns = rows, runs }(indexRows, maxRuns)
+	indexRows, maxRuns = 4, 2
+
+	disk := newDiskTable([]string{"A", "B"}, t.TempDir())
+	disk.SetDistinct(true)
+	for i := 0; i < 200; i++ {
+		_, added := disk.AddTuple([]int{i % 50, -(i % 50)})
+		if added != (i < 50) {
+			t.Fatalf("AddTuple(%v) = %v", i%50, added)
+		}
+	}
+	if disk.Size() != 50 || len(disk.set.mem) >= indexRows || len(disk.set.runs) > maxRuns {
+		t.Errorf("Size() = %v, %v hashes in memory, %v runs", disk.Size(), len(disk.set.mem), len(disk.set.runs))
+	}
+
+	other := newDiskTable([]string{"A"}, t.TempDir())
+	for i := 0; i < 30; i++ {
+		other.AddTuple([]int{i % 7})
+	}
+	Distinct(other)
+	if other.set != nil || other.Size() != 7 || other.Value(6, 0) != 6 {
+		t.Errorf("distinct table=\n%s", RelToString(other))
+	}
+}
+
+func TestSpilledJoin(t *testing.T) {
+	l := NewRelation([]string{"A", "B"})
+	r := NewRelation([]string{"B", "C"})
+	for i := 0; i < 100; i++ {
+		l.AddTuple([]int{i, i % 10})
+		r.AddTuple([]int{i % 13, i})
+	}
+	exp := make(map[string]bool)
+	for _, tup := range Join(l, r).Tuples() {
+		exp[fmt.Sprint(tup)] = true
+	}
+
+	SetMemoryBudget(1, t.TempDir())
+	defer SetMemoryBudget(0, "")
+	for _, res := range []Relation{Join(l, r), ParJoin(l, r)} {
+		if res.Size() != len(exp) {
+			t.Errorf("join has %v tuples, expected %v", res.Size(), len(exp))
+		}
+		for _, tup := range res.Tuples() {
+			if !exp[fmt.Sprint(tup)] {
+				t.Errorf("unexpected tuple %v", tup)
+			}
+		}
+	}
+}
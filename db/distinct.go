@@ -0,0 +1,105 @@
+package db
+
+// tupleIndex maps the hash of a tuple to the positions of the tuples with that hash
+type tupleIndex map[uint64][]int
+
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+func hashValue(h uint64, v int) uint64 {
+	x := uint64(v)
+	for k := 0; k < 8; k++ {
+		h ^= x & 0xff
+		h *= fnvPrime
+		x >>= 8
+	}
+	return h
+}
+
+func hashTuple(vals []int) uint64 {
+	h := uint64(fnvOffset)
+	for _, v := range vals {
+		h = hashValue(h, v)
+	}
+	return h
+}
+
+func hashRow(r Relation, i int) uint64 {
+	h := uint64(fnvOffset)
+	for col := range r.Attributes() {
+		h = hashValue(h, r.Value(i, col))
+	}
+	return h
+}
+
+func sameRow(r Relation, i int, vals []int) bool {
+	for col, v := range vals {
+		if r.Value(i, col) != v {
+			return false
+		}
+	}
+	return true
+}
+
+// find the position of a live tuple equal to vals
+func (idx tupleIndex) find(r Relation, h uint64, vals []int) (int, bool) {
+	for _, i := range idx[h] {
+		if r.Live(i) && sameRow(r, i, vals) {
+			return i, true
+		}
+	}
+	return -1, false
+}
+
+func buildIndex(r Relation) tupleIndex {
+	idx := make(tupleIndex)
+	for i := 0; i < r.Len(); i++ {
+		if r.Live(i) {
+			h := hashRow(r, i)
+			idx[h] = append(idx[h], i)
+		}
+	}
+	return idx
+}
+
+// Distinct removes duplicate tuples from a relation, keeping their first occurrence
+func Distinct(r Relation) (Relation, bool) {
+	switch d := r.(type) {
+	case *spillTable:
+		_, res := Distinct(d.Relation)
+		return r, res
+	case *diskTable:
+		if d.set != nil {
+			return r, false
+		}
+		idx, res := d.removeDuplicates()
+		if err := idx.close(); err != nil {
+			panic(err)
+		}
+		if d.needsCompaction(d.rows) {
+			d.Compact()
+		}
+		return r, res
+	}
+	idx := make(tupleIndex)
+	var tupToDel []int
+	for i := 0; i < r.Len(); i++ {
+		if !r.Live(i) {
+			continue
+		}
+		tup := r.Tuple(i)
+		h := hashTuple(tup)
+		if _, found := idx.find(r, h, tup); found {
+			tupToDel = append(tupToDel, i)
+		} else {
+			idx[h] = append(idx[h], i)
+		}
+	}
+	res, err := r.RemoveTuples(tupToDel)
+	if err != nil {
+		panic(err)
+	}
+	return r, res
+}
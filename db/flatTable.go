@@ -0,0 +1,140 @@
+package db
+
+// flatTable stores tuples row by row in a single array of dictionary
+// encoded values
+type flatTable struct {
+	attrs   []string
+	attrPos map[string]int
+	data    []int32
+
+	codes  map[int]int32
+	values []int
+	deletions
+	sortedness
+	set tupleIndex
+}
+
+func newFlatTable(attrs []string) *flatTable {
+	return &flatTable{
+		attrs:      attrs,
+		attrPos:    makeAttrPos(attrs),
+		codes:      make(map[int]int32),
+		sortedness: newSortedness(len(attrs)),
+	}
+}
+
+func (t *flatTable) encode(v int) int32 {
+	if c, ok := t.codes[v]; ok {
+		return c
+	}
+	c := int32(len(t.values))
+	t.codes[v] = c
+	t.values = append(t.values, v)
+	return c
+}
+
+func (t *flatTable) Empty() bool {
+	return t.Size() == 0
+}
+
+func (t *flatTable) Attributes() []string {
+	return t.attrs
+}
+
+func (t *flatTable) Position(attr string) (pos int, ok bool) {
+	pos, ok = t.attrPos[attr]
+	return
+}
+
+// AddTuple to this relation. It returns the tuple and false if the
+// arity is wrong (nil) or if the tuple is a duplicate under set semantics.
+func (t *flatTable) AddTuple(vals []int) (Tuple, bool) {
+	if len(t.attrs) != len(vals) {
+		return nil, false
+	}
+	if t.set != nil {
+		h := hashTuple(vals)
+		if _, found := t.set.find(t, h, vals); found {
+			return vals, false
+		}
+		t.set[h] = append(t.set[h], t.Len())
+	}
+	for _, v := range vals {
+		t.data = append(t.data, t.encode(v))
+	}
+	t.track(vals)
+	return vals, true
+}
+
+// RemoveTuples marks the given tuples as deleted, and compacts the
+// relation when enough of its tuples are deleted
+func (t *flatTable) RemoveTuples(idx []int) (bool, error) {
+	res, err := t.markDead(idx, t.Len())
+	if err != nil {
+		return res, err
+	}
+	if t.needsCompaction(t.Len()) {
+		t.Compact()
+	}
+	return res, nil
+}
+
+func (t *flatTable) Compact() {
+	if t.numDead == 0 {
+		return
+	}
+	arity := len(t.attrs)
+	w := 0
+	for i := 0; i < t.Len(); i++ {
+		if t.Live(i) {
+			w += copy(t.data[w:], t.data[i*arity:(i+1)*arity])
+		}
+	}
+	t.data = t.data[:w]
+	t.reset()
+	if t.set != nil {
+		t.set = buildIndex(t)
+	}
+}
+
+func (t *flatTable) SetDistinct(on bool) {
+	if !on {
+		t.set = nil
+		return
+	}
+	Distinct(t)
+	t.Compact()
+	t.set = buildIndex(t)
+}
+
+// Tuples decodes the tuples of this relation that have not been deleted
+func (t *flatTable) Tuples() []Tuple {
+	res := make([]Tuple, 0, t.Size())
+	for i := 0; i < t.Len(); i++ {
+		if t.Live(i) {
+			res = append(res, t.Tuple(i))
+		}
+	}
+	return res
+}
+
+func (t *flatTable) Len() int {
+	return len(t.data) / len(t.attrs)
+}
+
+func (t *flatTable) Size() int {
+	return t.Len() - t.numDead
+}
+
+func (t *flatTable) Value(i int, col int) int {
+	return t.values[t.data[i*len(t.attrs)+col]]
+}
+
+func (t *flatTable) Tuple(i int) Tuple {
+	arity := len(t.attrs)
+	tup := make(Tuple, arity)
+	for j, c := range t.data[i*arity : (i+1)*arity] {
+		tup[j] = t.values[c]
+	}
+	return tup
+}
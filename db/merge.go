@@ -0,0 +1,115 @@
+package db
+
+// mergeKey is the number of leading attributes that l and r have in common,
+// in the same positions, and on which both of them are sorted.
+// A sort-merge join on these columns is possible if it is positive.
+func mergeKey(l Relation, r Relation) int {
+	m := l.SortedPrefix()
+	if r.SortedPrefix() < m {
+		m = r.SortedPrefix()
+	}
+	lAttrs, rAttrs := l.Attributes(), r.Attributes()
+	for k := 0; k < m; k++ {
+		if lAttrs[k] != rAttrs[k] {
+			return k
+		}
+	}
+	return m
+}
+
+// compareKey compares the first m columns of the i-th tuple of l and of the j-th tuple of r
+func compareKey(l Relation, i int, r Relation, j int, m int) int {
+	for c := 0; c < m; c++ {
+		lv, rv := l.Value(i, c), r.Value(j, c)
+		if lv < rv {
+			return -1
+		} else if lv > rv {
+			return 1
+		}
+	}
+	return 0
+}
+
+// nextLive finds the first live tuple of r from the i-th one
+func nextLive(r Relation, i int) int {
+	for i < r.Len() && !r.Live(i) {
+		i++
+	}
+	return i
+}
+
+// groupEnd finds the end of the group of tuples of r with the same key as the i-th one
+func groupEnd(r Relation, i int, m int) int {
+	end := i + 1
+	for end < r.Len() && (!r.Live(end) || compareKey(r, i, r, end, m) == 0) {
+		end++
+	}
+	return end
+}
+
+// mergeGroups calls f on each pair of groups of l and r with the same key
+// on the first m columns, which must be sorted in both relations
+func mergeGroups(l Relation, r Relation, m int, f func(lStart, lEnd, rStart, rEnd int)) {
+	i, j := nextLive(l, 0), nextLive(r, 0)
+	for i < l.Len() && j < r.Len() {
+		switch cmp := compareKey(l, i, r, j, m); {
+		case cmp < 0:
+			i = nextLive(l, i+1)
+		case cmp > 0:
+			j = nextLive(r, j+1)
+		default:
+			lEnd, rEnd := groupEnd(l, i, m), groupEnd(r, j, m)
+			f(i, lEnd, j, rEnd)
+			i, j = nextLive(l, lEnd), nextLive(r, rEnd)
+		}
+	}
+}
+
+// mergeSemijoin removes from l the tuples that do not join with r, merging
+// the relations on their first m columns
+func mergeSemijoin(l Relation, lCols []int, r Relation, rCols []int, m int) (Relation, bool) {
+	keep := make([]bool, l.Len())
+	mergeGroups(l, r, m, func(lStart, lEnd, rStart, rEnd int) {
+		for i := lStart; i < lEnd; i++ {
+			if !l.Live(i) {
+				continue
+			}
+			for j := rStart; j < rEnd; j++ {
+				if r.Live(j) && (m == len(lCols) || sameCols(l, i, lCols, r, j, rCols)) {
+					keep[i] = true
+					break
+				}
+			}
+		}
+	})
+	var tupToDel []int
+	for i := 0; i < l.Len(); i++ {
+		if l.Live(i) && !keep[i] {
+			tupToDel = append(tupToDel, i)
+		}
+	}
+	res, err := l.RemoveTuples(tupToDel)
+	if err != nil {
+		panic(err)
+	}
+	return l, res
+}
+
+// mergeJoin joins l and r merging them on their first m columns
+func mergeJoin(l Relation, lCols []int, r Relation, rCols []int, m int) Relation {
+	newAttrs := joinedAttrs(l, r)
+	newRel := NewRelation(newAttrs)
+	mergeGroups(l, r, m, func(lStart, lEnd, rStart, rEnd int) {
+		for i := lStart; i < lEnd; i++ {
+			if !l.Live(i) {
+				continue
+			}
+			for j := rStart; j < rEnd; j++ {
+				if r.Live(j) && (m == len(lCols) || sameCols(l, i, lCols, r, j, rCols)) {
+					newRel.AddTuple(joinedTuple(newAttrs, l, i, r, j))
+				}
+			}
+		}
+	})
+	return newRel
+}
@@ -0,0 +1,137 @@
+package db
+
+import "sort"
+
+// trie of the tuples of a relation, one level per attribute
+type trie struct {
+	keys     []int
+	children map[int]*trie
+}
+
+func newTrie() *trie {
+	return &trie{children: make(map[int]*trie)}
+}
+
+func (t *trie) insert(vals []int) {
+	curr := t
+	for _, v := range vals {
+		next, ok := curr.children[v]
+		if !ok {
+			next = newTrie()
+			curr.children[v] = next
+			curr.keys = append(curr.keys, v)
+		}
+		curr = next
+	}
+}
+
+func (t *trie) sortKeys() {
+	sort.Ints(t.keys)
+	for _, c := range t.children {
+		c.sortKeys()
+	}
+}
+
+// MultiJoin computes the natural join of several relations at once with
+// the Generic Join algorithm, which is worst-case optimal. Attributes of
+// the result are ordered by decreasing number of relations they occur in.
+// Relations without attributes only tell whether the join is empty, and
+// the result is nil if no relation has attributes (see NewRelation).
+func MultiJoin(rels []Relation) Relation {
+	count := make(map[string]int)
+	var order []string
+	for _, r := range rels {
+		for _, a := range r.Attributes() {
+			if count[a] == 0 {
+				order = append(order, a)
+			}
+			count[a]++
+		}
+	}
+	if len(order) == 0 {
+		return nil
+	}
+	sort.SliceStable(order, func(i, j int) bool { return count[order[i]] > count[order[j]] })
+	res := NewRelation(order)
+	for _, r := range rels {
+		if len(r.Attributes()) == 0 && r.Empty() {
+			return res
+		}
+	}
+	rank := make(map[string]int)
+	for i, a := range order {
+		rank[a] = i
+	}
+
+	// relsAt[d] are the relations containing the d-th attribute
+	relsAt := make([][]int, len(order))
+	roots := make([]*trie, len(rels))
+	for k, r := range rels {
+		attrs := append([]string{}, r.Attributes()...)
+		sort.Slice(attrs, func(i, j int) bool { return rank[attrs[i]] < rank[attrs[j]] })
+		cols := make([]int, len(attrs))
+		for i, a := range attrs {
+			cols[i], _ = r.Position(a)
+			relsAt[rank[a]] = append(relsAt[rank[a]], k)
+		}
+		roots[k] = newTrie()
+		vals := make([]int, len(cols))
+		for i := 0; i < r.Len(); i++ {
+			if !r.Live(i) {
+				continue
+			}
+			for j, c := range cols {
+				vals[j] = r.Value(i, c)
+			}
+			roots[k].insert(vals)
+		}
+		roots[k].sortKeys()
+	}
+
+	gj := genericJoin{relsAt: relsAt, res: res}
+	gj.run(0, roots, make([]int, len(order)))
+	return res
+}
+
+type genericJoin struct {
+	relsAt [][]int
+	res    Relation
+}
+
+func (gj *genericJoin) run(depth int, nodes []*trie, tup []int) {
+	if depth == len(gj.relsAt) {
+		newTup := make(Tuple, len(tup))
+		copy(newTup, tup)
+		gj.res.AddTuple(newTup)
+		return
+	}
+	rels := gj.relsAt[depth]
+	smallest := rels[0]
+	for _, k := range rels[1:] {
+		if len(nodes[k].keys) < len(nodes[smallest].keys) {
+			smallest = k
+		}
+	}
+	saved := make([]*trie, len(rels))
+	for i, k := range rels {
+		saved[i] = nodes[k]
+	}
+	for _, v := range nodes[smallest].keys {
+		found := true
+		for i, k := range rels {
+			next, ok := saved[i].children[v]
+			if !ok {
+				found = false
+				break
+			}
+			nodes[k] = next
+		}
+		if found {
+			tup[depth] = v
+			gj.run(depth+1, nodes, tup)
+		}
+	}
+	for i, k := range rels {
+		nodes[k] = saved[i]
+	}
+}
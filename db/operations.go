@@ -1,53 +1,64 @@
 package db
 
+import "fmt"
+
 type Condition func(t Tuple) bool
 
+// Semijoin removes from l the tuples that do not join with any tuple of r.
+// It merges the relations if they are sorted on their common attributes,
+// and it hashes r otherwise.
 func Semijoin(l Relation, r Relation) (Relation, bool) {
 	joinIdx := commonAttrs(l, r)
 	if len(joinIdx) == 0 {
 		return l, false
 	}
-
-	var tupToDel []int
-	for i, leftTup := range l.Tuples() {
-		delete := true
-		for _, rightTup := range r.Tuples() {
-			if match(leftTup, rightTup, joinIdx) {
-				delete = false
-				break
-			}
-		}
-		if delete {
-			tupToDel = append(tupToDel, i)
-		}
+	lCols, rCols := splitJoinIdx(joinIdx)
+	if m := mergeKey(l, r); m > 0 {
+		return mergeSemijoin(l, lCols, r, rCols, m)
 	}
+	return removeMatching(l, lCols, r, rCols, false)
+}
 
-	res, err := l.RemoveTuples(tupToDel)
-	if err != nil {
-		panic(err)
+// Join l and r. It merges the relations if they are sorted on their
+// common attributes, and it hashes r otherwise. Under a memory budget,
+// the relations are hashed a partition at a time (see SetMemoryBudget).
+func Join(l Relation, r Relation) Relation {
+	lCols, rCols := splitJoinIdx(commonAttrs(l, r))
+	if m := mergeKey(l, r); m > 0 {
+		return mergeJoin(l, lCols, r, rCols, m)
 	}
-	return l, res
+	if len(lCols) > 0 && spilling(l, r) {
+		return spilledJoin(l, lCols, r, rCols)
+	}
+	return hashJoin(l, lCols, r, rCols)
 }
 
-func Join(l Relation, r Relation) Relation {
-	newAttrs := joinedAttrs(l, r)
-	joinIdx := commonAttrs(l, r)
-	newRel := NewRelation(newAttrs)
-	for _, lTup := range l.Tuples() {
-		for _, rTup := range r.Tuples() {
-			if match(lTup, rTup, joinIdx) {
-				newTup := joinedTuple(newAttrs, lTup, rTup, r.Position)
-				newRel.AddTuple(newTup)
+func hashJoin(l Relation, lCols []int, r Relation, rCols []int) Relation {
+	newRel := NewRelation(joinedAttrs(l, r))
+	joinInto(newRel, l, lCols, r, rCols)
+	return newRel
+}
+
+// joinInto adds to newRel the join of l and r, hashing r
+func joinInto(newRel Relation, l Relation, lCols []int, r Relation, rCols []int) {
+	newAttrs := newRel.Attributes()
+	idx := hashIndex(r, rCols)
+	for i := 0; i < l.Len(); i++ {
+		if !l.Live(i) {
+			continue
+		}
+		for _, j := range idx[hashCols(l, i, lCols)] {
+			if sameCols(l, i, lCols, r, j, rCols) {
+				newRel.AddTuple(joinedTuple(newAttrs, l, i, r, j))
 			}
 		}
 	}
-	return newRel
 }
 
 func Select(r Relation, c Condition) (Relation, bool) {
 	var tupToDel []int
-	for i, tup := range r.Tuples() {
-		if !c(tup) {
+	for i := 0; i < r.Len(); i++ {
+		if r.Live(i) && !c(r.Tuple(i)) {
 			tupToDel = append(tupToDel, i)
 		}
 	}
@@ -76,15 +87,6 @@ func commonAttrs(left Relation, right Relation) [][]int {
 	return out
 }
 
-func match(left Tuple, right Tuple, joinIndex [][]int) bool {
-	for _, z := range joinIndex {
-		if left[z[0]] != right[z[1]] {
-			return false
-		}
-	}
-	return true
-}
-
 func joinedAttrs(l Relation, r Relation) []string {
 	var res []string
 	res = append(res, l.Attributes()...)
@@ -96,12 +98,159 @@ func joinedAttrs(l Relation, r Relation) []string {
 	return res
 }
 
-func joinedTuple(attrs []string, lTup Tuple, rTup Tuple, rPos func(string) (int, bool)) Tuple {
+func joinedTuple(attrs []string, l Relation, i int, r Relation, j int) Tuple {
+	arity := len(l.Attributes())
 	res := make([]int, 0, len(attrs))
-	res = append(res, lTup...)
-	for _, v := range attrs[len(lTup):] {
-		i, _ := rPos(v)
-		res = append(res, rTup[i])
+	for k := 0; k < arity; k++ {
+		res = append(res, l.Value(i, k))
+	}
+	for _, v := range attrs[arity:] {
+		k, _ := r.Position(v)
+		res = append(res, r.Value(j, k))
 	}
 	return res
 }
+
+// Project a relation onto the given attributes, removing duplicates.
+// Relations without attributes are not supported, so attrs cannot be empty.
+func Project(r Relation, attrs []string) Relation {
+	if len(attrs) == 0 {
+		panic("Cannot project on no attributes")
+	}
+	cols := make([]int, len(attrs))
+	for i, a := range attrs {
+		pos, found := r.Position(a)
+		if !found {
+			panic("Cannot project on missing attribute " + a)
+		}
+		cols[i] = pos
+	}
+	newRel := NewRelation(attrs)
+	newRel.SetDistinct(true)
+	for i := 0; i < r.Len(); i++ {
+		if !r.Live(i) {
+			continue
+		}
+		newTup := make(Tuple, len(cols))
+		for j, c := range cols {
+			newTup[j] = r.Value(i, c)
+		}
+		newRel.AddTuple(newTup)
+	}
+	return newRel
+}
+
+// Union adds to l the tuples of r, which must have the same attributes
+func Union(l Relation, r Relation) (Relation, bool) {
+	cols := sameAttrs(l, r)
+	res := false
+	for i := 0; i < r.Len(); i++ {
+		if !r.Live(i) {
+			continue
+		}
+		newTup := make(Tuple, len(cols))
+		for j, c := range cols {
+			newTup[j] = r.Value(i, c)
+		}
+		if _, added := l.AddTuple(newTup); added {
+			res = true
+		}
+	}
+	return l, res
+}
+
+// Difference removes from l the tuples of r, which must have the same attributes
+func Difference(l Relation, r Relation) (Relation, bool) {
+	cols := sameAttrs(l, r)
+	lCols := make([]int, len(cols))
+	for i := range lCols {
+		lCols[i] = i
+	}
+	return removeMatching(l, lCols, r, cols, true)
+}
+
+// Antijoin removes from l the tuples that join with some tuple of r
+func Antijoin(l Relation, r Relation) (Relation, bool) {
+	lCols, rCols := splitJoinIdx(commonAttrs(l, r))
+	return removeMatching(l, lCols, r, rCols, true)
+}
+
+// removeMatching deletes from l the tuples that match (or not) some tuple of r on the given columns
+func removeMatching(l Relation, lCols []int, r Relation, rCols []int, matching bool) (Relation, bool) {
+	idx := hashIndex(r, rCols)
+	var tupToDel []int
+	for i := 0; i < l.Len(); i++ {
+		if l.Live(i) && idx.probe(l, i, lCols, r, rCols) == matching {
+			tupToDel = append(tupToDel, i)
+		}
+	}
+	res, err := l.RemoveTuples(tupToDel)
+	if err != nil {
+		panic(err)
+	}
+	return l, res
+}
+
+// sameAttrs finds for every attribute of l its position in r
+func sameAttrs(l Relation, r Relation) []int {
+	if len(l.Attributes()) != len(r.Attributes()) {
+		panic(fmt.Sprintf("Attributes %v and %v differ", l.Attributes(), r.Attributes()))
+	}
+	cols := make([]int, len(l.Attributes()))
+	for i, a := range l.Attributes() {
+		pos, found := r.Position(a)
+		if !found {
+			panic(fmt.Sprintf("Attributes %v and %v differ", l.Attributes(), r.Attributes()))
+		}
+		cols[i] = pos
+	}
+	return cols
+}
+
+func splitJoinIdx(joinIdx [][]int) ([]int, []int) {
+	lCols := make([]int, len(joinIdx))
+	rCols := make([]int, len(joinIdx))
+	for i, z := range joinIdx {
+		lCols[i], rCols[i] = z[0], z[1]
+	}
+	return lCols, rCols
+}
+
+func hashCols(r Relation, i int, cols []int) uint64 {
+	h := uint64(fnvOffset)
+	for _, c := range cols {
+		h = hashValue(h, r.Value(i, c))
+	}
+	return h
+}
+
+// hashIndex of the live tuples of r on the given columns
+func hashIndex(r Relation, cols []int) tupleIndex {
+	idx := make(tupleIndex)
+	for i := 0; i < r.Len(); i++ {
+		if r.Live(i) {
+			h := hashCols(r, i, cols)
+			idx[h] = append(idx[h], i)
+		}
+	}
+	return idx
+}
+
+// probe tells whether the i-th tuple of l matches some tuple of r indexed by idx
+func (idx tupleIndex) probe(l Relation, i int, lCols []int, r Relation, rCols []int) bool {
+	for _, j := range idx[hashCols(l, i, lCols)] {
+		if sameCols(l, i, lCols, r, j, rCols) {
+			return true
+		}
+	}
+	return false
+}
+
+func sameCols(l Relation, i int, lCols []int, r Relation, j int, rCols []int) bool {
+	for k, c := range lCols {
+		if l.Value(i, c) != r.Value(j, rCols[k]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,247 @@
+package db
+
+import "testing"
+
+func opsTestData() (Relation, Relation) {
+	rAttrs := []string{"Y", "Z", "U"}
+	rRel := []Tuple{{3, 8, 9}, {9, 3, 8}, {8, 3, 8}, {3, 8, 4}, {3, 8, 3}, {8, 9, 4}, {9, 4, 7}}
+	sAttrs := []string{"Z", "U", "W"}
+	sRel := []Tuple{{8, 9, 1}, {3, 8, 2}, {4, 7, 3}, {4, 7, 4}}
+	return InitializedRelation(rAttrs, rRel), InitializedRelation(sAttrs, sRel)
+}
+
+func TestProjectNoAttrs(t *testing.T) {
+	r, _ := opsTestData()
+	defer func() {
+		if recover() == nil {
+			t.Error("projection on no attributes did not fail")
+		}
+	}()
+	Project(r, []string{})
+}
+
+func TestProject(t *testing.T) {
+	r, _ := opsTestData()
+	res := Project(r, []string{"Z", "Y"})
+	expected := InitializedRelation([]string{"Z", "Y"}, []Tuple{{8, 3}, {3, 9}, {3, 8}, {9, 8}, {4, 9}})
+	if !relEquals(res, expected) {
+		t.Errorf("project(r) =\n%s, expected\n%s", RelToString(res), RelToString(expected))
+	}
+}
+
+func TestUnion(t *testing.T) {
+	r, _ := opsTestData()
+	r.SetDistinct(true)
+	oth := InitializedRelation([]string{"U", "Y", "Z"}, []Tuple{{9, 3, 8}, {1, 2, 3}})
+	if _, res := Union(r, oth); !res {
+		t.Error("union(r, oth) did not change r")
+	}
+	expected, _ := opsTestData()
+	expected.AddTuple([]int{2, 3, 1})
+	if !relEquals(r, expected) {
+		t.Errorf("union(r, oth) =\n%s, expected\n%s", RelToString(r), RelToString(expected))
+	}
+}
+
+func TestDifference(t *testing.T) {
+	r, _ := opsTestData()
+	oth := InitializedRelation([]string{"U", "Y", "Z"}, []Tuple{{9, 3, 8}, {8, 8, 3}, {1, 2, 3}})
+	if _, res := Difference(r, oth); !res {
+		t.Error("difference(r, oth) did not change r")
+	}
+	expected := InitializedRelation(r.Attributes(), []Tuple{{9, 3, 8}, {3, 8, 4}, {3, 8, 3}, {8, 9, 4}, {9, 4, 7}})
+	if !relEquals(r, expected) {
+		t.Errorf("difference(r, oth) =\n%s, expected\n%s", RelToString(r), RelToString(expected))
+	}
+}
+
+func TestAntijoin(t *testing.T) {
+	r, s := opsTestData()
+	if _, res := Antijoin(r, s); !res {
+		t.Error("antijoin(r, s) did not change r")
+	}
+	expected := InitializedRelation(r.Attributes(), []Tuple{{3, 8, 4}, {3, 8, 3}, {8, 9, 4}})
+	if !relEquals(r, expected) {
+		t.Errorf("antijoin(r, s) =\n%s, expected\n%s", RelToString(r), RelToString(expected))
+	}
+
+	r, s = opsTestData()
+	Semijoin(r, s)
+	expected = InitializedRelation(r.Attributes(), []Tuple{{3, 8, 9}, {9, 3, 8}, {8, 3, 8}, {9, 4, 7}})
+	if !relEquals(r, expected) {
+		t.Errorf("semijoin(r, s) =\n%s, expected\n%s", RelToString(r), RelToString(expected))
+	}
+
+	r, _ = opsTestData()
+	Antijoin(r, InitializedRelation([]string{"A"}, []Tuple{{1}}))
+	if !r.Empty() {
+		t.Errorf("antijoin(r, a) =\n%s, expected empty", RelToString(r))
+	}
+}
+
+func TestJoin(t *testing.T) {
+	r, s := opsTestData()
+	res := Join(r, s)
+	expected := InitializedRelation([]string{"Y", "Z", "U", "W"}, []Tuple{{3, 8, 9, 1}, {9, 3, 8, 2}, {8, 3, 8, 2}, {9, 4, 7, 3}, {9, 4, 7, 4}})
+	if !relEquals(res, expected) {
+		t.Errorf("join(r, s) =\n%s, expected\n%s", RelToString(res), RelToString(expected))
+	}
+}
+
+func TestMultiJoin(t *testing.T) {
+	r, s := opsTestData()
+	v := InitializedRelation([]string{"W", "Y"}, []Tuple{{1, 3}, {2, 8}, {3, 9}, {4, 5}})
+	res := MultiJoin([]Relation{r, s, v})
+	expected := Join(Join(r, s), v)
+	if res.Size() != expected.Size() || res.Size() != 3 {
+		t.Fatalf("multijoin =\n%s, expected\n%s", RelToString(res), RelToString(expected))
+	}
+	Difference(expected, res)
+	if !expected.Empty() {
+		t.Errorf("multijoin misses\n%s", RelToString(expected))
+	}
+}
+
+func TestMultiJoinNoAttrs(t *testing.T) {
+	if res := MultiJoin(nil); res != nil {
+		t.Errorf("multijoin of no relations =\n%s", RelToString(res))
+	}
+	r, _ := opsTestData()
+	unit := &table{attrPos: make(map[string]int), sortedness: newSortedness(0)}
+	if res := MultiJoin([]Relation{r, unit}); !res.Empty() {
+		t.Errorf("multijoin with an empty relation =\n%s", RelToString(res))
+	}
+	unit.AddTuple([]int{})
+	if res := MultiJoin([]Relation{unit, r}); res.Size() != r.Size() {
+		t.Errorf("multijoin with the unit relation =\n%s, expected\n%s", RelToString(res), RelToString(r))
+	}
+}
+
+func sortedTestData() (Relation, Relation) {
+	lRel := []Tuple{{1, 1, 5}, {1, 2, 3}, {2, 1, 1}, {2, 1, 4}, {4, 7, 2}, {5, 5, 5}}
+	rRel := []Tuple{{1, 2, 9}, {2, 1, 1}, {2, 2, 7}, {3, 3, 6}, {5, 6, 5}, {5, 5, 4}}
+	return InitializedRelation([]string{"A", "B", "C"}, lRel), InitializedRelation([]string{"A", "D", "B"}, rRel)
+}
+
+func TestSortedPrefix(t *testing.T) {
+	l, r := sortedTestData()
+	if l.SortedPrefix() != 3 || r.SortedPrefix() != 1 {
+		t.Errorf("sorted prefixes %v and %v, expected 3 and 1", l.SortedPrefix(), r.SortedPrefix())
+	}
+	if m := mergeKey(l, r); m != 1 {
+		t.Errorf("merge key %v, expected 1", m)
+	}
+	l.AddTuple([]int{5, 4, 9})
+	if l.SortedPrefix() != 1 {
+		t.Errorf("sorted prefix %v, expected 1", l.SortedPrefix())
+	}
+	l.AddTuple([]int{3, 9, 9})
+	if mergeKey(l, r) != 0 {
+		t.Errorf("merge key %v, expected 0", mergeKey(l, r))
+	}
+}
+
+func TestMergeJoin(t *testing.T) {
+	l, r := sortedTestData()
+	l.RemoveTuples([]int{1})
+	lCols, rCols := splitJoinIdx(commonAttrs(l, r))
+	res := mergeJoin(l, lCols, r, rCols, 1)
+	expected := hashJoin(l, lCols, r, rCols)
+	if res.Size() != 3 || !relEquals(res, expected) {
+		t.Errorf("join(l, r) =\n%s, expected\n%s", RelToString(res), RelToString(expected))
+	}
+
+	mergeSemijoin(l, lCols, r, rCols, 1)
+	expected, _ = sortedTestData()
+	expected.RemoveTuples([]int{1})
+	nestedSemijoin(expected, r)
+	if l.Size() != 3 || !relEquals(l, expected) {
+		t.Errorf("semijoin(l, r) =\n%s, expected\n%s", RelToString(l), RelToString(expected))
+	}
+}
+
+func TestParSemijoin(t *testing.T) {
+	l, r := benchRelations(500)
+	expected, _ := benchRelations(500)
+	l.RemoveTuples([]int{2, 4})
+	expected.RemoveTuples([]int{2, 4})
+	ParSemijoin(l, r)
+	lCols, rCols := splitJoinIdx(commonAttrs(expected, r))
+	removeMatching(expected, lCols, r, rCols, false)
+	if l.Size() != 248 || !relEquals(l, expected) {
+		t.Errorf("parallel semijoin has %v tuples, expected %v", l.Size(), expected.Size())
+	}
+}
+
+func TestParJoin(t *testing.T) {
+	r, s := opsTestData()
+	res := ParJoin(r, s)
+	lCols, rCols := splitJoinIdx(commonAttrs(r, s))
+	expected := hashJoin(r, lCols, s, rCols)
+	if res.Size() != expected.Size() {
+		t.Fatalf("parallel join(r, s) =\n%s, expected\n%s", RelToString(res), RelToString(expected))
+	}
+	Difference(expected, res)
+	if !expected.Empty() {
+		t.Errorf("parallel join(r, s) misses\n%s", RelToString(expected))
+	}
+}
+
+// nestedSemijoin and nestedJoin are the nested loop versions of the
+// operators, used as a reference and a baseline in tests and benchmarks
+func nestedSemijoin(l Relation, r Relation) (Relation, bool) {
+	joinIdx := commonAttrs(l, r)
+	if len(joinIdx) == 0 {
+		return l, false
+	}
+
+	var tupToDel []int
+	for i := 0; i < l.Len(); i++ {
+		if !l.Live(i) {
+			continue
+		}
+		delete := true
+		for j := 0; j < r.Len(); j++ {
+			if r.Live(j) && match(l, i, r, j, joinIdx) {
+				delete = false
+				break
+			}
+		}
+		if delete {
+			tupToDel = append(tupToDel, i)
+		}
+	}
+
+	res, err := l.RemoveTuples(tupToDel)
+	if err != nil {
+		panic(err)
+	}
+	return l, res
+}
+
+func nestedJoin(l Relation, r Relation) Relation {
+	newAttrs := joinedAttrs(l, r)
+	joinIdx := commonAttrs(l, r)
+	newRel := NewRelation(newAttrs)
+	for i := 0; i < l.Len(); i++ {
+		if !l.Live(i) {
+			continue
+		}
+		for j := 0; j < r.Len(); j++ {
+			if r.Live(j) && match(l, i, r, j, joinIdx) {
+				newTup := joinedTuple(newAttrs, l, i, r, j)
+				newRel.AddTuple(newTup)
+			}
+		}
+	}
+	return newRel
+}
+
+func match(l Relation, i int, r Relation, j int, joinIndex [][]int) bool {
+	for _, z := range joinIndex {
+		if l.Value(i, z[0]) != r.Value(j, z[1]) {
+			return false
+		}
+	}
+	return true
+}
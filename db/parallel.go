@@ -0,0 +1,139 @@
+package db
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// parallelFor calls f(0), ..., f(n-1) on a pool of runtime.NumCPU() workers
+func parallelFor(n int, f func(k int)) {
+	jobs := make(chan int, n)
+	for k := 0; k < n; k++ {
+		jobs <- k
+	}
+	close(jobs)
+
+	numWorkers := runtime.NumCPU()
+	if n < numWorkers {
+		numWorkers = n
+	}
+	var wg sync.WaitGroup
+	wg.Add(numWorkers)
+	for w := 0; w < numWorkers; w++ {
+		go func() {
+			defer wg.Done()
+			for k := range jobs {
+				f(k)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// partition the live tuples of r by the hash of the given columns.
+// It also returns the hash of every tuple.
+func partition(r Relation, cols []int, parts int) ([][]int, []uint64) {
+	hashes := make([]uint64, r.Len())
+	chunk := (r.Len() + parts - 1) / parts
+	parallelFor(parts, func(k int) {
+		for i := k * chunk; i < (k+1)*chunk && i < r.Len(); i++ {
+			if r.Live(i) {
+				hashes[i] = hashCols(r, i, cols)
+			}
+		}
+	})
+	res := make([][]int, parts)
+	for i, h := range hashes {
+		if r.Live(i) {
+			p := h % uint64(parts)
+			res[p] = append(res[p], i)
+		}
+	}
+	return res, hashes
+}
+
+// partitionIndex builds a hash index of the given tuples
+func partitionIndex(part []int, hashes []uint64) tupleIndex {
+	idx := make(tupleIndex)
+	for _, j := range part {
+		idx[hashes[j]] = append(idx[hashes[j]], j)
+	}
+	return idx
+}
+
+// ParSemijoin is a Semijoin that hash-partitions both relations on their
+// common attributes and processes the partitions in parallel
+func ParSemijoin(l Relation, r Relation) (Relation, bool) {
+	joinIdx := commonAttrs(l, r)
+	if len(joinIdx) == 0 {
+		return l, false
+	}
+	lCols, rCols := splitJoinIdx(joinIdx)
+	parts := runtime.NumCPU()
+	lParts, lHashes := partition(l, lCols, parts)
+	rParts, rHashes := partition(r, rCols, parts)
+
+	toDel := make([][]int, parts)
+	parallelFor(parts, func(p int) {
+		idx := partitionIndex(rParts[p], rHashes)
+		for _, i := range lParts[p] {
+			found := false
+			for _, j := range idx[lHashes[i]] {
+				if sameCols(l, i, lCols, r, j, rCols) {
+					found = true
+					break
+				}
+			}
+			if !found {
+				toDel[p] = append(toDel[p], i)
+			}
+		}
+	})
+
+	var tupToDel []int
+	for _, d := range toDel {
+		tupToDel = append(tupToDel, d...)
+	}
+	sort.Ints(tupToDel)
+	res, err := l.RemoveTuples(tupToDel)
+	if err != nil {
+		panic(err)
+	}
+	return l, res
+}
+
+// ParJoin is a Join that hash-partitions both relations on their common
+// attributes and processes the partitions in parallel. The tuples of the
+// result are grouped by partition, so their order differs from Join.
+func ParJoin(l Relation, r Relation) Relation {
+	lCols, rCols := splitJoinIdx(commonAttrs(l, r))
+	if len(lCols) > 0 && spilling(l, r) {
+		return spilledJoin(l, lCols, r, rCols)
+	}
+	parts := runtime.NumCPU()
+	lParts, lHashes := partition(l, lCols, parts)
+	rParts, rHashes := partition(r, rCols, parts)
+
+	newAttrs := joinedAttrs(l, r)
+	tuples := make([][]Tuple, parts)
+	parallelFor(parts, func(p int) {
+		idx := partitionIndex(rParts[p], rHashes)
+		for _, i := range lParts[p] {
+			for _, j := range idx[lHashes[i]] {
+				if sameCols(l, i, lCols, r, j, rCols) {
+					tuples[p] = append(tuples[p], joinedTuple(newAttrs, l, i, r, j))
+				}
+			}
+		}
+	})
+
+	newRel := NewRelation(newAttrs)
+	for p, tups := range tuples {
+		for _, tup := range tups {
+			newRel.AddTuple(tup)
+		}
+		tuples[p] = nil
+	}
+	return newRel
+}
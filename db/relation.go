@@ -12,6 +12,51 @@ type Relation interface {
 	RemoveTuples(idx []int) (bool, error)
 	Tuples() []Tuple
 	Empty() bool
+	// Len counts the tuples of a relation, including the deleted ones
+	Len() int
+	// Live tells whether the i-th tuple has not been deleted
+	Live(i int) bool
+	// Size counts the tuples of a relation that have not been deleted
+	Size() int
+	Value(i int, col int) int
+	Tuple(i int) Tuple
+	// Compact drops the deleted tuples and renumbers the others
+	Compact()
+	// SetDistinct turns set semantics on or off. When on, duplicates are
+	// removed and AddTuple does not add tuples that are already present.
+	SetDistinct(on bool)
+	// SortedPrefix is the number of leading attributes on which the
+	// tuples are in lexicographic order
+	SortedPrefix() int
+}
+
+// Impl selects how a relation stores its tuples
+type Impl int
+
+const (
+	// RowImpl stores every tuple in its own slice
+	RowImpl Impl = iota
+	// FlatImpl stores dictionary encoded tuples in a single array
+	FlatImpl
+	// DiskImpl stores tuples in a file (see SetMemoryBudget for its directory)
+	DiskImpl
+)
+
+// DefaultImpl is used by NewRelation
+var DefaultImpl = RowImpl
+
+// ParseImpl converts the name of an implementation into an Impl
+func ParseImpl(name string) (Impl, error) {
+	switch name {
+	case "row":
+		return RowImpl, nil
+	case "flat":
+		return FlatImpl, nil
+	case "disk":
+		return DiskImpl, nil
+	default:
+		return RowImpl, fmt.Errorf("%v relations not implemented", name)
+	}
 }
 
 // Tuple represent a row in a relation
@@ -21,32 +66,58 @@ type table struct {
 	attrs   []string
 	attrPos map[string]int
 	tuples  []Tuple
+	deletions
+	sortedness
+	set tupleIndex
 }
 
+// NewRelation creates an empty relation using DefaultImpl
 func NewRelation(attrs []string) Relation {
+	return NewRelationOf(DefaultImpl, attrs)
+}
+
+// NewRelationOf creates an empty relation using the given implementation
+func NewRelationOf(impl Impl, attrs []string) Relation {
 	if len(attrs) <= 0 {
 		return nil
 	}
+	var r Relation
+	switch impl {
+	case DiskImpl:
+		return newDiskTable(attrs, spillDir)
+	case FlatImpl:
+		r = newFlatTable(attrs)
+	default:
+		r = &table{attrs: attrs, attrPos: makeAttrPos(attrs), tuples: make([]Tuple, 0), sortedness: newSortedness(len(attrs))}
+	}
+	if memBudget > 0 {
+		return &spillTable{Relation: r}
+	}
+	return r
+}
+
+func makeAttrPos(attrs []string) map[string]int {
 	attrPos := make(map[string]int)
 	for i, v := range attrs {
 		attrPos[v] = i
 	}
-	return &table{attrs, attrPos, make([]Tuple, 0)}
+	return attrPos
 }
 
+// InitializedRelation creates a relation with the given tuples using DefaultImpl
 func InitializedRelation(attrs []string, rel []Tuple) Relation {
-	if len(attrs) <= 0 {
+	r := NewRelation(attrs)
+	if r == nil {
 		return nil
 	}
-	attrPos := make(map[string]int)
-	for i, v := range attrs {
-		attrPos[v] = i
+	for _, tup := range rel {
+		r.AddTuple(tup)
 	}
-	return &table{attrs, attrPos, rel}
+	return r
 }
 
 func (t *table) Empty() bool {
-	return len(t.tuples) == 0
+	return t.Size() == 0
 }
 
 func (t *table) Attributes() []string {
@@ -58,37 +129,85 @@ func (t *table) Position(attr string) (pos int, ok bool) {
 	return
 }
 
+// AddTuple to this relation. It returns the tuple and false if the
+// arity is wrong (nil) or if the tuple is a duplicate under set semantics.
 func (t *table) AddTuple(vals []int) (Tuple, bool) {
 	if len(t.attrs) != len(vals) {
 		return nil, false
 	}
 	// TODO check domains?
-	// TODO no check if the tuple is already here
+	if t.set != nil {
+		h := hashTuple(vals)
+		if _, found := t.set.find(t, h, vals); found {
+			return vals, false
+		}
+		t.set[h] = append(t.set[h], len(t.tuples))
+	}
 	t.tuples = append(t.tuples, vals)
+	t.track(vals)
 	return vals, true
 }
 
+func (t *table) SetDistinct(on bool) {
+	if !on {
+		t.set = nil
+		return
+	}
+	Distinct(t)
+	t.Compact()
+	t.set = buildIndex(t)
+}
+
+// RemoveTuples marks the given tuples as deleted, and compacts the
+// relation when enough of its tuples are deleted
 func (t *table) RemoveTuples(idx []int) (bool, error) {
-	if len(idx) == 0 {
-		return false, nil
-	}
-	newSize := len(t.tuples) - len(idx)
-	if newSize < 0 {
-		return false, fmt.Errorf("new size %v < 0", newSize)
-	}
-	newTuples := make([]Tuple, 0, newSize)
-	if newSize > 0 {
-		i := 0
-		for _, j := range idx {
-			newTuples = append(newTuples, t.tuples[i:j]...)
-			i = j + 1
-		}
-		newTuples = append(newTuples, t.tuples[i:]...)
+	res, err := t.markDead(idx, len(t.tuples))
+	if err != nil {
+		return res, err
+	}
+	if t.needsCompaction(len(t.tuples)) {
+		t.Compact()
+	}
+	return res, nil
+}
+
+func (t *table) Compact() {
+	if t.numDead == 0 {
+		return
+	}
+	t.tuples = t.Tuples()
+	t.reset()
+	if t.set != nil {
+		t.set = buildIndex(t)
 	}
-	t.tuples = newTuples
-	return true, nil
 }
 
+// Tuples of this relation that have not been deleted
 func (t *table) Tuples() []Tuple {
-	return t.tuples
+	if t.numDead == 0 {
+		return t.tuples
+	}
+	res := make([]Tuple, 0, t.Size())
+	for i, tup := range t.tuples {
+		if t.Live(i) {
+			res = append(res, tup)
+		}
+	}
+	return res
+}
+
+func (t *table) Len() int {
+	return len(t.tuples)
+}
+
+func (t *table) Size() int {
+	return len(t.tuples) - t.numDead
+}
+
+func (t *table) Value(i int, col int) int {
+	return t.tuples[i][col]
+}
+
+func (t *table) Tuple(i int) Tuple {
+	return t.tuples[i]
 }
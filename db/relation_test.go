@@ -0,0 +1,123 @@
+package db
+
+import "testing"
+
+func relEquals(r1 Relation, r2 Relation) bool {
+	if len(r1.Attributes()) != len(r2.Attributes()) || r1.Size() != r2.Size() {
+		return false
+	}
+	for i, a := range r1.Attributes() {
+		if r2.Attributes()[i] != a {
+			return false
+		}
+	}
+	tups1, tups2 := r1.Tuples(), r2.Tuples()
+	for i := range tups1 {
+		for j := range tups1[i] {
+			if tups1[i][j] != tups2[i][j] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func fill(r Relation, tuples []Tuple) Relation {
+	for _, tup := range tuples {
+		r.AddTuple(tup)
+	}
+	return r
+}
+
+func TestFlatTable(t *testing.T) {
+	attrs := []string{"Y", "Z", "U"}
+	tuples := []Tuple{{3, 8, 9}, {9, 3, 8}, {8, 3, 8}, {3, 8, 4}, {3, 8, 3}, {8, 9, 4}, {-9, 4, 7}}
+	row := fill(NewRelationOf(RowImpl, attrs), tuples)
+	flat := fill(NewRelationOf(FlatImpl, attrs), tuples)
+	if !relEquals(row, flat) {
+		t.Fatalf("flat=\n%s, expected\n%s", RelToString(flat), RelToString(row))
+	}
+	if tup := flat.Tuple(6); tup[0] != -9 || tup[1] != 4 || tup[2] != 7 {
+		t.Errorf("flat.Tuple(6) = %v, expected [-9 4 7]", tup)
+	}
+	if _, added := flat.AddTuple([]int{1, 2}); added {
+		t.Error("added a tuple with wrong arity")
+	}
+
+	idx := []int{0, 2, 6}
+	row.RemoveTuples(idx)
+	flat.RemoveTuples(idx)
+	if !relEquals(row, flat) {
+		t.Errorf("flat=\n%s, expected\n%s", RelToString(flat), RelToString(row))
+	}
+
+	other := []Tuple{{8, 7}, {3, 5}}
+	Semijoin(row, fill(NewRelationOf(RowImpl, []string{"U", "W"}), other))
+	Semijoin(flat, fill(NewRelationOf(FlatImpl, []string{"U", "W"}), other))
+	if !relEquals(row, flat) || row.Size() != 2 {
+		t.Errorf("flat=\n%s, expected\n%s", RelToString(flat), RelToString(row))
+	}
+}
+
+func TestLazyDeletion(t *testing.T) {
+	for _, impl := range []Impl{RowImpl, FlatImpl} {
+		r := fill(NewRelationOf(impl, []string{"A", "B"}), []Tuple{{1, 2}, {3, 4}, {5, 6}, {7, 8}, {9, 10}})
+		if res, _ := r.RemoveTuples([]int{1}); !res {
+			t.Errorf("impl %v: tuple 1 not removed", impl)
+		}
+		if res, _ := r.RemoveTuples([]int{1}); res {
+			t.Errorf("impl %v: tuple 1 removed twice", impl)
+		}
+		if r.Len() != 5 || r.Size() != 4 || r.Live(1) || !r.Live(2) {
+			t.Errorf("impl %v: Len() = %v, Size() = %v", impl, r.Len(), r.Size())
+		}
+		if tups := r.Tuples(); len(tups) != 4 || tups[1][0] != 5 {
+			t.Errorf("impl %v: Tuples() = %v", impl, tups)
+		}
+
+		r.RemoveTuples([]int{0, 4})
+		if r.Len() != 2 || r.Size() != 2 || r.Value(0, 0) != 5 || r.Value(1, 1) != 8 {
+			t.Errorf("impl %v: relation not compacted\n%s", impl, RelToString(r))
+		}
+		if _, err := r.RemoveTuples([]int{2}); err == nil {
+			t.Errorf("impl %v: removed a tuple out of range", impl)
+		}
+	}
+}
+
+func TestDistinct(t *testing.T) {
+	for _, impl := range []Impl{RowImpl, FlatImpl} {
+		r := fill(NewRelationOf(impl, []string{"A", "B"}), []Tuple{{1, 2}, {3, 4}, {1, 2}, {5, 6}, {3, 4}})
+		if _, res := Distinct(r); !res {
+			t.Errorf("impl %v: no duplicates removed", impl)
+		}
+		if tups := r.Tuples(); len(tups) != 3 || tups[0][0] != 1 || tups[1][0] != 3 || tups[2][0] != 5 {
+			t.Errorf("impl %v: Tuples() = %v", impl, tups)
+		}
+
+		r.SetDistinct(true)
+		if tup, added := r.AddTuple([]int{5, 6}); added || tup == nil {
+			t.Errorf("impl %v: duplicate added", impl)
+		}
+		if _, added := r.AddTuple([]int{6, 5}); !added {
+			t.Errorf("impl %v: new tuple not added", impl)
+		}
+		r.RemoveTuples([]int{0, 1, 2})
+		if _, added := r.AddTuple([]int{1, 2}); !added || r.Size() != 2 {
+			t.Errorf("impl %v: deleted tuple not added again", impl)
+		}
+		r.SetDistinct(false)
+		if _, added := r.AddTuple([]int{1, 2}); !added || r.Size() != 3 {
+			t.Errorf("impl %v: duplicate not added without set semantics", impl)
+		}
+	}
+}
+
+func TestInitializedRelationImpl(t *testing.T) {
+	defer func(impl Impl) { DefaultImpl = impl }(DefaultImpl)
+	DefaultImpl = FlatImpl
+	r := InitializedRelation([]string{"A", "B"}, []Tuple{{1, 2}, {3, 4}})
+	if _, ok := r.(*flatTable); !ok || r.Size() != 2 || r.Value(1, 1) != 4 {
+		t.Errorf("InitializedRelation = %T with %v, expected a flat table", r, r.Tuples())
+	}
+}
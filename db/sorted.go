@@ -0,0 +1,35 @@
+package db
+
+// sortedness tracks the longest prefix of the attributes of a relation on
+// which its tuples are in lexicographic order
+type sortedness struct {
+	prefix int
+	last   []int
+}
+
+func newSortedness(arity int) sortedness {
+	return sortedness{prefix: arity}
+}
+
+// SortedPrefix is the number of leading attributes on which the tuples are sorted
+func (s *sortedness) SortedPrefix() int {
+	return s.prefix
+}
+
+// track a tuple appended to the relation. The prefix shrinks to the first
+// column where the new tuple is smaller than the previous one.
+func (s *sortedness) track(vals []int) {
+	if s.last == nil {
+		s.last = make([]int, len(vals))
+	} else {
+		for c := 0; c < s.prefix; c++ {
+			if vals[c] != s.last[c] {
+				if vals[c] < s.last[c] {
+					s.prefix = c
+				}
+				break
+			}
+		}
+	}
+	copy(s.last, vals)
+}
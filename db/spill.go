@@ -0,0 +1,118 @@
+package db
+
+import (
+	"runtime/metrics"
+	"sync"
+)
+
+// checkEvery is the number of added tuples between two checks of the memory budget
+const checkEvery = 4096
+
+// partitionRows is the number of tuples of the right relation that a
+// partition of a spilled join should hold
+const partitionRows = 1 << 16
+
+var memBudget uint64
+var spillDir string
+
+// SetMemoryBudget makes new relations move their tuples to files in dir
+// when the heap grows beyond the given number of bytes (0 means no budget).
+// Joins then write their inputs to dir in partitions, and hash one at a time.
+func SetMemoryBudget(bytes uint64, dir string) {
+	memBudget = bytes
+	spillDir = dir
+}
+
+var heapSample = []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+var heapMu sync.Mutex
+
+func overBudget() bool {
+	heapMu.Lock()
+	defer heapMu.Unlock()
+	metrics.Read(heapSample)
+	return heapSample[0].Value.Uint64() > memBudget
+}
+
+// spillTable is an in-memory relation that moves to disk when the
+// memory budget is exhausted
+type spillTable struct {
+	Relation
+	added    int
+	spilled  bool
+	distinct bool
+}
+
+func (t *spillTable) AddTuple(vals []int) (Tuple, bool) {
+	tup, added := t.Relation.AddTuple(vals)
+	if added && !t.spilled {
+		t.added++
+		if t.added%checkEvery == 0 && overBudget() {
+			t.spill()
+		}
+	}
+	return tup, added
+}
+
+func (t *spillTable) SetDistinct(on bool) {
+	t.distinct = on
+	t.Relation.SetDistinct(on)
+}
+
+func (t *spillTable) spill() {
+	disk := newDiskTable(t.Attributes(), spillDir)
+	if t.distinct {
+		disk.SetDistinct(true)
+	}
+	for i := 0; i < t.Len(); i++ {
+		if t.Live(i) {
+			disk.AddTuple(t.Tuple(i))
+		}
+	}
+	t.Relation = disk
+	t.spilled = true
+}
+
+// spilling tells whether a join of l and r must keep its partitions on disk
+func spilling(l Relation, r Relation) bool {
+	return memBudget > 0 && (onDisk(l) || onDisk(r) || overBudget())
+}
+
+func onDisk(r Relation) bool {
+	switch t := r.(type) {
+	case *diskTable:
+		return true
+	case *spillTable:
+		return t.spilled
+	}
+	return false
+}
+
+// spilledJoin is a hash join that writes l and r to disk in partitions
+// by the hash of their common attributes, and joins a partition at a time
+func spilledJoin(l Relation, lCols []int, r Relation, rCols []int) Relation {
+	parts := r.Size()/partitionRows + 1
+	lParts := spillPartitions(l, lCols, parts)
+	rParts := spillPartitions(r, rCols, parts)
+	newRel := NewRelation(joinedAttrs(l, r))
+	for p := 0; p < parts; p++ {
+		joinInto(newRel, lParts[p], lCols, rParts[p], rCols)
+		lParts[p].free()
+		rParts[p].free()
+	}
+	return newRel
+}
+
+// spillPartitions writes the live tuples of r to files by the hash of the given columns
+func spillPartitions(r Relation, cols []int, parts int) []*diskTable {
+	res := make([]*diskTable, parts)
+	for p := range res {
+		res[p] = newDiskTable(r.Attributes(), spillDir)
+	}
+	for i := 0; i < r.Len(); i++ {
+		if r.Live(i) {
+			p := hashCols(r, i, cols) % uint64(parts)
+			res[p].AddTuple(r.Tuple(i))
+		}
+	}
+	return res
+}
@@ -18,7 +18,11 @@ func RelToString(r Relation) string {
 		}
 	}
 	sb.WriteByte('\n')
-	for _, tup := range r.Tuples() {
+	for k := 0; k < r.Len(); k++ {
+		if !r.Live(k) {
+			continue
+		}
+		tup := r.Tuple(k)
 		for i, v := range tup {
 			sb.WriteString(strconv.Itoa(v))
 			if i < len(tup) {
@@ -32,10 +36,13 @@ func RelToString(r Relation) string {
 
 func RelToSolutions(r Relation) []csp.Solution {
 	var res []csp.Solution
-	for _, tup := range r.Tuples() {
+	for k := 0; k < r.Len(); k++ {
+		if !r.Live(k) {
+			continue
+		}
 		sol := make(csp.Solution)
 		for i, v := range r.Attributes() {
-			sol[v] = tup[i]
+			sol[v] = r.Value(k, i)
 		}
 		res = append(res, sol)
 	}
@@ -56,7 +63,11 @@ func RelToFile(filename string, r Relation) {
 
 	w := bufio.NewWriter(table)
 	var sb strings.Builder
-	for _, tup := range r.Tuples() {
+	for k := 0; k < r.Len(); k++ {
+		if !r.Live(k) {
+			continue
+		}
+		tup := r.Tuple(k)
 		for i, v := range tup {
 			sb.WriteString(strconv.Itoa(v))
 			if i < len(tup)-1 {
@@ -70,4 +81,7 @@ func RelToFile(filename string, r Relation) {
 		}
 		sb.Reset()
 	}
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
 }
@@ -1,17 +1,14 @@
 package decomp
 
 import (
-	"bufio"
 	"bytes"
+	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
-	"regexp"
 	"runtime"
 	"strconv"
-	"strings"
-	"sync"
 
 	"github.com/dmlongo/callidus/csp"
 	"github.com/dmlongo/callidus/db"
@@ -32,94 +29,42 @@ func init() {
 	nacre = filepath.Dir(path) + "/libs/nacre"
 }
 
-var listRegex = regexp.MustCompile(`.*<list>(.*)</list>.*`)
-var valuesRegex = regexp.MustCompile(`.*<values>(.*)</values>.*`)
-
 // SolveSubCspSeq solve the CSPs associated to a hypertree sequentially
-func SolveSubCspSeq(ht Hypertree, domains map[string]string, constraints map[string]csp.Constraint, baseDir string) bool {
+func SolveSubCspSeq(ctx context.Context, ht Hypertree, domains map[string]string, constraints map[string]csp.Constraint, baseDir string, opts SubOptions) (bool, error) {
 	subCspFolder := files.MakeDir(baseDir + "subs/")
+	ctx, cancel := withLimit(ctx, opts.Timeout)
+	defer cancel()
 
-	sat := true
 	for _, node := range ht {
-		nodeCtrs, nodeVars := filterCtrsVars(node, constraints, domains)
-		subFile := subCspFolder + "sub" + strconv.Itoa(node.ID) + ".xml"
-		csp.CreateXCSPInstance(nodeCtrs, nodeVars, subFile)
-		sat = solveCSPSeq(subFile, node)
-		if !sat {
-			break
-		}
-	}
-	return sat
-}
-
-func solveCSPSeq(cspFile string, node *Node) bool {
-	cmd := exec.Command(nacre, cspFile, "-complete", "-sols", "-verb=3")
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		panic(err)
-	}
-	if err := cmd.Start(); err != nil {
-		panic(err)
-	}
-
-	res := readTuples(bufio.NewReader(stdout), cspFile, node)
-	if err := cmd.Wait(); err != nil {
-		if ee, ok := err.(*exec.ExitError); ok && ee.ExitCode() != 40 {
-			panic(fmt.Sprintf("nacre failed: %v:", err))
+		sat, err := solveNode(ctx, node, constraints, domains, subCspFolder, opts)
+		if err != nil || !sat {
+			return false, err
 		}
 	}
-	return res
+	return true, nil
 }
 
-func readTuples(reader *bufio.Reader, cspFile string, node *Node) bool {
-	solFound := false
-	for {
-		line, eof := files.ReadLine(reader)
-		if eof {
-			break
-		}
-		if strings.HasPrefix(line, "v") {
-			tup := makeTuple(line, cspFile, node.bagSet)
-			if _, added := node.Table.AddTuple(tup); !added {
-				panic(fmt.Sprintf("node %v, %s: Could not add tuple %v", node.ID, cspFile, tup))
-			}
-			solFound = true
-		}
-	}
-	return solFound
-}
-
-func makeTuple(line string, cspFile string, bag map[string]int) db.Tuple {
-	matchesVal := valuesRegex.FindStringSubmatch(line)
-	if len(matchesVal) < 2 {
-		panic(cspFile + ", bad values= " + line)
-	}
-	matchesList := listRegex.FindStringSubmatch(line)
-	if len(matchesList) < 2 {
-		panic(cspFile + ", bad list= " + line)
-	}
-	list := strings.Split(strings.TrimSpace(matchesList[1]), " ")
+// makeTuple of the values of the variables in bag, which maps them to their positions
+func makeTuple(vars []string, vals []int, bag map[string]int) db.Tuple {
 	tup := make([]int, len(bag))
 	z := 0
-	for i, value := range strings.Split(strings.TrimSpace(matchesVal[1]), " ") {
-		v, err := strconv.Atoi(value)
-		if err != nil {
-			panic(err)
-		}
-		if _, ok := bag[list[i]]; ok {
-			tup[z] = v
+	for i, v := range vars {
+		if pos, ok := bag[v]; ok {
+			tup[pos] = vals[i]
 			z++
 		}
 	}
 	if z != len(bag) {
-		panic(fmt.Sprintf("Did not find enough variables %v/%v, list: %v", z, len(bag), list))
+		panic(fmt.Sprintf("Did not find enough variables %v/%v, list: %v", z, len(bag), vars))
 	}
 	return tup
 }
 
 // SolveSubCspPar solve the CSPs associated to a hypertree in parallel
-func SolveSubCspPar(ht Hypertree, domains map[string]string, constraints map[string]csp.Constraint, baseDir string) bool {
+func SolveSubCspPar(ctx context.Context, ht Hypertree, domains map[string]string, constraints map[string]csp.Constraint, baseDir string, opts SubOptions) (bool, error) {
 	subCspFolder := files.MakeDir(baseDir + "subs/")
+	ctx, cancel := withLimit(ctx, opts.Timeout)
+	defer cancel() // stops the other solvers when returning early
 
 	jobs := make(chan *Node)
 	go func() {
@@ -129,98 +74,83 @@ func SolveSubCspPar(ht Hypertree, domains map[string]string, constraints map[str
 		close(jobs)
 	}()
 
-	sat := make(chan bool)
-	quit := make(chan bool)
-	defer close(quit)
+	type result struct {
+		sat bool
+		err error
+	}
+	results := make(chan result)
 	numNodes := len(ht)
 	numWorkers := runtime.NumCPU()
-	var wg sync.WaitGroup
-	wg.Add(numNodes)
 	if numNodes < numWorkers {
 		numWorkers = numNodes
 	}
 	for i := 0; i < numWorkers; i++ {
 		go func() { // launch a worker
 			for n := range jobs {
-				nodeCtrs, nodeVars := filterCtrsVars(n, constraints, domains)
-				subFile := subCspFolder + "sub" + strconv.Itoa(n.ID) + ".xml"
-				csp.CreateXCSPInstance(nodeCtrs, nodeVars, subFile)
-				solveCSPPar(subFile, n, sat, quit)
-				wg.Done()
+				sat, err := solveNode(ctx, n, constraints, domains, subCspFolder, opts)
+				select {
+				case results <- result{sat, err}:
+				case <-ctx.Done():
+				}
 			}
 		}()
 	}
-	go func() {
-		wg.Wait()
-		close(sat)
-	}()
 
+	// results are dropped once ctx is done, so ctx must be checked too
 	for i := 0; i < len(ht); i++ {
-		if !<-sat {
-			return false
+		select {
+		case res := <-results:
+			if res.err != nil || !res.sat {
+				return false, res.err
+			}
+		case <-ctx.Done():
+			return false, limitError(ctx, -1)
 		}
 	}
-	return true
+	return true, nil
 }
 
-func solveCSPPar(cspFile string, node *Node, sat chan<- bool, quit <-chan bool) {
-	cmd := exec.Command(nacre, cspFile, "-complete", "-sols", "-verb=3")
-	stdout, err := cmd.StdoutPipe()
-	var stderr bytes.Buffer
-	cmd.Stderr = &stderr
-	if err != nil {
-		panic(err)
-	}
-	if err := cmd.Start(); err != nil {
-		panic(err)
+// solveNode computes the table of a node, and tells whether it is not empty.
+// The sub-CSP is streamed to the solver if it reads standard input, and its
+// file is written only if opts.KeepFiles is set or the solver needs it.
+func solveNode(ctx context.Context, n *Node, constraints map[string]csp.Constraint, domains map[string]string, subCspFolder string, opts SubOptions) (bool, error) {
+	if solveExtensional(n, constraints, domains) {
+		return !n.Table.Empty(), nil
 	}
+	nodeCtrs, nodeVars := filterCtrsVars(n, constraints, domains)
+	var instance bytes.Buffer
+	csp.CreateXCSPInstance(nodeCtrs, nodeVars, &instance)
 
-	res := false
-	tuples := fetchTuples(bufio.NewReader(stdout), cspFile, node, quit)
-	for tup := range tuples {
-		select {
-		case <-quit:
-			err = cmd.Process.Kill()
-			if err != nil {
-				panic(err)
-			}
-			return
-		default:
-			res = true
-			if _, added := node.Table.AddTuple(tup); !added {
-				panic(fmt.Sprintf("node %v, %s: Tuple arity does not match with relation arity %v", node.ID, cspFile, len(node.Table.Attributes())))
-			}
-		}
-	}
-	if err := cmd.Wait(); err != nil {
-		if ee, ok := err.(*exec.ExitError); ok && ee.ExitCode() != 40 {
-			panic(fmt.Sprintf("nacre failed on %s: %v: %s", cspFile, err, stderr.String()))
+	var key string
+	var order []string
+	if opts.CacheDir != "" {
+		key, order = cacheKey(opts.solver(), instance.String(), nodeVars, n.bag)
+		if loadCached(opts.CacheDir, key, order, n) {
+			return !n.Table.Empty(), nil
 		}
 	}
-	sat <- res
-}
 
-func fetchTuples(r *bufio.Reader, cspFile string, node *Node, quit <-chan bool) <-chan []int {
-	out := make(chan []int) // TODO buffer maybe?
-	go func() {
-		defer close(out)
-		for {
-			select {
-			case <-quit:
-				return
-			default:
-				line, eof := files.ReadLine(r)
-				if eof {
-					return
-				}
-				if strings.HasPrefix(line, "v") {
-					tup := makeTuple(line, cspFile, node.bagSet)
-					out <- tup
-				}
-			}
+	subFile := ""
+	if opts.KeepFiles || !opts.solver().Stdin() {
+		subFile = subCspFolder + "sub" + strconv.Itoa(n.ID) + ".xml"
+		if err := ioutil.WriteFile(subFile, instance.Bytes(), 0644); err != nil {
+			panic(err)
 		}
-	}()
-	return out
+		if !opts.KeepFiles {
+			defer os.Remove(subFile)
+		}
+	}
+	sat, err := runSolver(ctx, subFile, instance.Bytes(), n, opts)
+	if err != nil {
+		return false, err
+	}
+	if !DistinctTables {
+		db.Distinct(n.Table) // solutions may differ only outside the bag
+	}
+	if opts.CacheDir != "" {
+		storeCached(opts.CacheDir, key, order, n)
+	}
+	return sat, nil
 }
 
 func filterCtrsVars(n *Node, ctrs map[string]csp.Constraint, doms map[string]string) ([]csp.Constraint, map[string]string) {
@@ -235,5 +165,118 @@ func filterCtrsVars(n *Node, ctrs map[string]csp.Constraint, doms map[string]str
 			}
 		}
 	}
-	return outCtrs, outVars
+	return projectTables(n, outCtrs, outVars)
+}
+
+// projectTables eliminates from a sub-CSP the variables outside the bag of
+// the node that occur in a single constraint, if it is a positive table, by
+// projecting the table on its other variables. Solvers then enumerate fewer
+// solutions that differ only outside the bag.
+func projectTables(n *Node, ctrs []csp.Constraint, vars map[string]string) ([]csp.Constraint, map[string]string) {
+	occurrences := make(map[string]int)
+	for _, c := range ctrs {
+		for _, v := range c.Variables() {
+			occurrences[v]++
+		}
+	}
+
+	outCtrs := make([]csp.Constraint, 0, len(ctrs))
+	for _, c := range ctrs {
+		scope, tuples, ok := csp.ExtensionTable(c)
+		if !ok {
+			outCtrs = append(outCtrs, c)
+			continue
+		}
+		var keep []int
+		for i, v := range scope {
+			if _, inBag := n.bagSet[v]; inBag || occurrences[v] > 1 {
+				keep = append(keep, i)
+			}
+		}
+		if len(keep) == len(scope) {
+			outCtrs = append(outCtrs, c)
+			continue
+		}
+
+		rel := extensionRelation(scope, tuples, vars)
+		if rel.Empty() {
+			outCtrs = append(outCtrs, c) // let the solver find out that the sub-CSP is unsatisfiable
+			continue
+		}
+		kept := make(map[string]bool)
+		keptVars := make([]string, len(keep))
+		for i, k := range keep {
+			keptVars[i] = scope[k]
+			kept[scope[k]] = true
+		}
+		for _, v := range scope {
+			if !kept[v] {
+				delete(vars, v)
+			}
+		}
+		if len(keptVars) > 0 {
+			var projected [][]int
+			for _, tup := range db.Project(rel, keptVars).Tuples() {
+				projected = append(projected, tup)
+			}
+			outCtrs = append(outCtrs, csp.NewExtensionCtr(c.Name(), keptVars, projected))
+		}
+	}
+	return outCtrs, vars
+}
+
+// solveExtensional computes the table of a node by joining the constraints
+// of its cover, if all of them are positive extensional constraints.
+// Nodes with an empty bag or cover are left to the solver.
+func solveExtensional(n *Node, ctrs map[string]csp.Constraint, doms map[string]string) bool {
+	if len(n.bag) == 0 {
+		return false
+	}
+	rels := make([]db.Relation, 0, len(n.Cover()))
+	for _, e := range n.Cover() {
+		vars, tuples, ok := csp.ExtensionTable(ctrs[e])
+		if !ok || len(vars) == 0 {
+			return false
+		}
+		rels = append(rels, extensionRelation(vars, tuples, doms))
+	}
+	if len(rels) == 0 {
+		return false
+	}
+	for _, v := range n.bag {
+		found := false
+		for _, r := range rels {
+			if _, found = r.Position(v); found {
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	n.Table = db.Project(db.MultiJoin(rels), n.bag)
+	n.Table.SetDistinct(DistinctTables) // a projection is already distinct
+	return true
+}
+
+func extensionRelation(vars []string, tuples [][]int, doms map[string]string) db.Relation {
+	domains := make([]csp.Domain, len(vars))
+	for i, v := range vars {
+		domains[i] = csp.ParseDomain(doms[v])
+	}
+	rel := db.NewRelation(vars)
+	for _, tup := range tuples {
+		inDomains := true
+		for i, v := range tup {
+			if !domains[i].Contains(v) {
+				inDomains = false
+				break
+			}
+		}
+		if inDomains {
+			rel.AddTuple(tup)
+		}
+	}
+	return rel
 }
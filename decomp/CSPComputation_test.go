@@ -0,0 +1,230 @@
+package decomp
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/dmlongo/callidus/csp"
+)
+
+func TestSolveExtensional(t *testing.T) {
+	ctrFile := writeTestFile(t, "test.ctr", `ExtensionCtr
+c1
+x y
+supports
+(0,1)(1,2)(2,0)(3,3)
+ExtensionCtr
+c2
+y z
+supports
+(1,5)(2,5)(2,6)(0,7)
+PrimitiveCtr
+c3
+x z
+ne(x,z)
+`)
+	ctrs := csp.ParseConstraints(ctrFile)
+	doms := map[string]string{"x": "0..2", "y": "0..3", "z": "5..7"}
+
+	n := NewNode(1, []string{"x", "z"}, []string{"c1", "c2"})
+	if !solveExtensional(n, ctrs, doms) {
+		t.Fatal("node 1 not solved")
+	}
+	expected := map[[2]int]bool{{0, 5}: true, {1, 5}: true, {1, 6}: true, {2, 7}: true}
+	if n.Table.Size() != len(expected) {
+		t.Errorf("table of node 1 has %v tuples, expected %v", n.Table.Size(), len(expected))
+	}
+	for _, tup := range n.Table.Tuples() {
+		if !expected[[2]int{tup[0], tup[1]}] {
+			t.Errorf("unexpected tuple %v", tup)
+		}
+	}
+
+	m := NewNode(2, []string{"x", "z"}, []string{"c1", "c3"})
+	if solveExtensional(m, ctrs, doms) {
+		t.Error("node 2 solved without nacre")
+	}
+	for _, e := range []*Node{NewNode(3, []string{"x"}, nil), NewNode(4, nil, []string{"c1"})} {
+		if solveExtensional(e, ctrs, doms) {
+			t.Errorf("node %v with an empty cover or bag solved without nacre", e.ID)
+		}
+	}
+}
+
+func pipelineTestTree() (*Node, Hypertree) {
+	root := NewNode(0, []string{"x", "y"}, []string{"c1"})
+	a := NewNode(1, []string{"y", "z"}, []string{"c2"})
+	b := NewNode(2, []string{"x", "w"}, []string{"c4"})
+	root.AddChild(a)
+	root.AddChild(b)
+	return root, Hypertree{root, a, b}
+}
+
+// slowSolverTest replaces the solver with one that never ends, and returns
+// constraints and domains for pipelineTestTree that only the solver can solve
+func slowSolverTest(t *testing.T) (map[string]csp.Constraint, map[string]string) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell")
+	}
+	fakeSolver(t, "sleep 30\n")
+	ctrFile := writeTestFile(t, "test.ctr", `PrimitiveCtr
+c1
+x y
+ne(x,y)
+PrimitiveCtr
+c2
+y z
+ne(y,z)
+PrimitiveCtr
+c4
+x w
+ne(x,w)
+`)
+	doms := map[string]string{"x": "0..3", "y": "0..3", "z": "0..3", "w": "0..3"}
+	return csp.ParseConstraints(ctrFile), doms
+}
+
+func TestSolveAndReduce(t *testing.T) {
+	ctrFile := writeTestFile(t, "test.ctr", `ExtensionCtr
+c1
+x y
+supports
+(0,1)(1,2)(2,0)(3,3)
+ExtensionCtr
+c2
+y z
+supports
+(1,5)(2,5)(2,6)(0,7)
+ExtensionCtr
+c4
+x w
+supports
+(0,0)(2,1)(3,1)
+ExtensionCtr
+c5
+x w
+supports
+(4,0)
+`)
+	ctrs := csp.ParseConstraints(ctrFile)
+	doms := map[string]string{"x": "0..3", "y": "0..3", "z": "5..7", "w": "0..1"}
+
+	root, tree := pipelineTestTree()
+	if sat, err := SolveAndReduce(context.Background(), root, doms, ctrs, t.TempDir()+"/", SubOptions{}); err != nil || !sat {
+		t.Fatal("pipeline is unsat, expected sat")
+	}
+	expRoot, expTree := pipelineTestTree()
+	if sat, err := SolveSubCspSeq(context.Background(), expTree, doms, ctrs, t.TempDir()+"/", SubOptions{}); err != nil || !sat || !(&seqY{}).reduce(context.Background(), expRoot) {
+		t.Fatal("seq is unsat, expected sat")
+	}
+	if !equals(root, expRoot) || root.Table.Size() != 2 {
+		t.Errorf("pipeline reduced the tree differently from seq")
+	}
+	for _, n := range tree {
+		if n.Table.Empty() {
+			t.Errorf("node %v is empty", n.ID)
+		}
+	}
+
+	root, tree = pipelineTestTree()
+	tree[2].SetCover([]string{"c5"})
+	if sat, _ := SolveAndReduce(context.Background(), root, doms, ctrs, t.TempDir()+"/", SubOptions{}); sat {
+		t.Error("pipeline is sat, expected unsat")
+	}
+}
+
+func TestProjectTables(t *testing.T) {
+	ctrFile := writeTestFile(t, "test.ctr", `ExtensionCtr
+c1
+x y
+supports
+(0,1)(1,2)(2,0)(3,3)(0,4)
+ExtensionCtr
+c2
+y w
+supports
+(1,1)(2,2)
+PrimitiveCtr
+c3
+x z
+ne(x,z)
+ExtensionCtr
+c4
+u v
+supports
+(1,1)
+`)
+	ctrs := csp.ParseConstraints(ctrFile)
+	doms := map[string]string{"x": "0..2", "y": "0..3", "z": "0..2", "w": "0..2", "u": "0..1", "v": "0..1"}
+
+	n := NewNode(1, []string{"x", "z"}, []string{"c1", "c3", "c4"})
+	outCtrs, outVars := filterCtrsVars(n, ctrs, doms)
+	if len(outCtrs) != 2 || len(outVars) != 2 {
+		t.Fatalf("%v constraints on %v, expected 2 on [x z]", len(outCtrs), outVars)
+	}
+	vars, tuples, ok := csp.ExtensionTable(outCtrs[0])
+	if !ok || len(vars) != 1 || vars[0] != "x" || len(tuples) != 3 {
+		t.Errorf("c1 projected on %v with %v, expected x with [0 1 2]", vars, tuples)
+	}
+
+	m := NewNode(2, []string{"x"}, []string{"c1", "c2"})
+	outCtrs, outVars = filterCtrsVars(m, ctrs, doms)
+	if len(outCtrs) != 2 || len(outVars) != 2 {
+		t.Errorf("%v constraints on %v, expected 2 on [x y]", len(outCtrs), outVars)
+	}
+}
+
+func TestSolveNodeDistinct(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell")
+	}
+	fakeSolver(t, `cat "$1" > /dev/null
+echo "v <instantiation> <list> x y </list> <values> 1 2 </values> </instantiation>"
+echo "v <instantiation> <list> x y </list> <values> 1 3 </values> </instantiation>"
+exit 40
+`)
+	ctrFile := writeTestFile(t, "test.ctr", `PrimitiveCtr
+c1
+x y
+lt(x,y)
+`)
+	ctrs := csp.ParseConstraints(ctrFile)
+	doms := map[string]string{"x": "0..3", "y": "0..3"}
+
+	n := NewNode(1, []string{"x"}, []string{"c1"})
+	if sat, err := SolveSubCspSeq(context.Background(), Hypertree{n}, doms, ctrs, t.TempDir()+"/", SubOptions{}); err != nil || !sat {
+		t.Fatalf("SolveSubCspSeq = %v, %v", sat, err)
+	}
+	if n.Table.Size() != 1 {
+		t.Errorf("table has %v tuples, expected 1", n.Table.Size())
+	}
+}
+
+func TestSolveTimeout(t *testing.T) {
+	ctrs, doms := slowSolverTest(t)
+	opts := SubOptions{Timeout: 200 * time.Millisecond}
+
+	solvers := map[string]func(root *Node, tree Hypertree) (bool, error){
+		"seq": func(root *Node, tree Hypertree) (bool, error) {
+			return SolveSubCspSeq(context.Background(), tree, doms, ctrs, t.TempDir()+"/", opts)
+		},
+		"par": func(root *Node, tree Hypertree) (bool, error) {
+			return SolveSubCspPar(context.Background(), tree, doms, ctrs, t.TempDir()+"/", opts)
+		},
+		"pipeline": func(root *Node, tree Hypertree) (bool, error) {
+			return SolveAndReduce(context.Background(), root, doms, ctrs, t.TempDir()+"/", opts)
+		},
+	}
+	for name, solve := range solvers {
+		root, tree := pipelineTestTree()
+		start := time.Now()
+		if sat, err := solve(root, tree); sat || err == nil || err.Error() != "timeout in sub-CSPs" {
+			t.Errorf("%s = %v, %v, expected timeout in sub-CSPs", name, sat, err)
+		}
+		if d := time.Since(start); d > 10*time.Second {
+			t.Errorf("%s stopped after %v", name, d)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package decomp
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dmlongo/callidus/csp"
+	"github.com/dmlongo/callidus/db"
+)
+
+// cacheKey of the table of a node whose sub-CSP is the given XCSP instance.
+// Nodes whose sub-CSPs and bags are the same up to renaming have the same key,
+// if they are solved by the same solver. It also returns the bag in canonical
+// order, which is the order of the attributes of cached tables.
+func cacheKey(solver Solver, instance string, vars map[string]string, bag []string) (string, []string) {
+	canon, renaming := csp.CanonicalForm(instance, vars)
+	index := func(v string) int {
+		i, _ := strconv.Atoi(strings.TrimPrefix(renaming[v], "V"))
+		return i
+	}
+	order := make([]string, len(bag))
+	copy(order, bag)
+	sort.Slice(order, func(i, j int) bool { return index(order[i]) < index(order[j]) })
+
+	h := sha256.New()
+	io.WriteString(h, solverID(solver))
+	io.WriteString(h, canon)
+	io.WriteString(h, "bag")
+	for _, v := range order {
+		io.WriteString(h, " "+renaming[v])
+	}
+	return hex.EncodeToString(h.Sum(nil)), order
+}
+
+// solverID tells solvers apart by their kind and their command line
+func solverID(s Solver) string {
+	return fmt.Sprintf("%T %q\n", s, s.Command("{}").Args)
+}
+
+func cacheFile(cacheDir string, key string) string {
+	return filepath.Join(cacheDir, key+".tab")
+}
+
+// loadCached adds to the table of a node the tuples cached under key, if any.
+// Their values are in the given order of the bag.
+func loadCached(cacheDir string, key string, order []string, n *Node) bool {
+	file, err := os.Open(cacheFile(cacheDir, key))
+	if os.IsNotExist(err) {
+		return false
+	} else if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+
+	rr, err := db.NewRelationReader(file)
+	if err != nil {
+		panic(err)
+	}
+	for {
+		tup, err := rr.Next()
+		if err == io.EOF {
+			return true
+		} else if err != nil {
+			panic(err)
+		}
+		t := make(db.Tuple, len(tup))
+		for i, v := range order {
+			t[n.bagSet[v]] = tup[i]
+		}
+		n.Table.AddTuple(t)
+	}
+}
+
+// storeCached saves the table of a node under key, with its attributes in the
+// given order. Tables appear in the cache only when complete, so that
+// concurrent runs can share it.
+func storeCached(cacheDir string, key string, order []string, n *Node) {
+	if err := os.MkdirAll(cacheDir, 0777); err != nil {
+		panic(err)
+	}
+	tmp, err := ioutil.TempFile(cacheDir, "tab*.tmp")
+	if err != nil {
+		panic(err)
+	}
+	if err := tmp.Close(); err != nil {
+		panic(err)
+	}
+	db.RelToBinFile(tmp.Name(), db.Project(n.Table, order))
+	if err := os.Rename(tmp.Name(), cacheFile(cacheDir, key)); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,81 @@
+package decomp
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/dmlongo/callidus/csp"
+)
+
+func TestSolveCached(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell")
+	}
+	calls := filepath.Join(t.TempDir(), "calls")
+	// the only solution of lt(p,q) on 0..1 is p=0 q=1, and variables are written sorted
+	fakeSolver(t, `echo call >> `+calls+`
+inst=$(cat "$1")
+echo "v <instantiation> <list> $(echo "$inst" | grep -o 'id="[^"]*"' | cut -d'"' -f2 | tr '\n' ' ') </list>"
+if echo "$inst" | grep -q 'lt(a,b)\|lt(d,c)'; then echo "v <values> 0 1 </values> </instantiation>"; else echo "v <values> 1 0 </values> </instantiation>"; fi
+exit 40
+`)
+	ctrFile := writeTestFile(t, "test.ctr", `PrimitiveCtr
+c1
+a b
+lt(a,b)
+PrimitiveCtr
+c2
+c d
+lt(d,c)
+`)
+	ctrs := csp.ParseConstraints(ctrFile)
+	doms := map[string]string{"a": "0..1", "b": "0..1", "c": "0..1", "d": "0..1"}
+	opts := SubOptions{CacheDir: t.TempDir()}
+
+	n1 := NewNode(1, []string{"a", "b"}, []string{"c1"})
+	n2 := NewNode(2, []string{"c", "d"}, []string{"c2"})
+	baseDir := t.TempDir() + "/"
+	sat, err := SolveSubCspSeq(context.Background(), Hypertree{n1, n2}, doms, ctrs, baseDir, opts)
+	if err != nil || !sat {
+		t.Fatalf("SolveSubCspSeq = %v, %v", sat, err)
+	}
+	if subs, _ := ioutil.ReadDir(baseDir + "subs/"); len(subs) > 0 {
+		t.Errorf("%v sub-CSP files left without KeepFiles", len(subs))
+	}
+	if out, _ := ioutil.ReadFile(calls); strings.Count(string(out), "call") != 1 {
+		t.Errorf("solver called %v times, expected once", strings.Count(string(out), "call"))
+	}
+	if n2.Table.Size() != 1 || n2.Table.Value(0, 0) != 1 || n2.Table.Value(0, 1) != 0 {
+		t.Errorf("cached table of node 2 is %v, expected [[1 0]]", n2.Table.Tuples())
+	}
+
+	// another run finds both tables in the cache
+	n3 := NewNode(3, []string{"d", "c"}, []string{"c2"})
+	if sat, err := SolveSubCspSeq(context.Background(), Hypertree{n3}, doms, ctrs, t.TempDir()+"/", opts); err != nil || !sat {
+		t.Fatalf("SolveSubCspSeq = %v, %v", sat, err)
+	}
+	if out, _ := ioutil.ReadFile(calls); strings.Count(string(out), "call") != 1 {
+		t.Error("solver called again")
+	}
+	if n3.Table.Size() != 1 || n3.Table.Value(0, 0) != 0 || n3.Table.Value(0, 1) != 1 {
+		t.Errorf("cached table of node 3 is %v, expected [[0 1]]", n3.Table.Tuples())
+	}
+
+	// the same sub-CSP solved by another command line is not in the cache
+	custom, err := NewSolver("nacre", nacre+" {} -complete", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Solver = custom
+	n4 := NewNode(4, []string{"c", "d"}, []string{"c2"})
+	if sat, err := SolveSubCspSeq(context.Background(), Hypertree{n4}, doms, ctrs, t.TempDir()+"/", opts); err != nil || !sat {
+		t.Fatalf("SolveSubCspSeq = %v, %v", sat, err)
+	}
+	if out, _ := ioutil.ReadFile(calls); strings.Count(string(out), "call") != 2 {
+		t.Error("table of another solver taken from the cache")
+	}
+}
@@ -3,6 +3,7 @@ package decomp
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -23,22 +24,24 @@ func init() {
 	hgtools = filepath.Dir(path) + "/libs/hgtools.jar"
 }
 
-// Convert a CSP into a hypergraph
-func Convert(cspPath string, outDir string) Hypergraph {
+// Convert a CSP into a hypergraph. The converter is killed when ctx is done.
+func Convert(ctx context.Context, cspPath string, outDir string) (Hypergraph, error) {
 	// TODO add logging
 	cmd := exec.Command("java", "-jar", hgtools, "-convert", "-xcsp", "-print", "-out", outDir, cspPath)
-	var stderr bytes.Buffer
+	setProcessGroup(cmd)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
-	stdout, err := cmd.StdoutPipe()
-	if err != nil {
-		panic(err)
-	}
 	if err := cmd.Start(); err != nil {
 		panic(err)
 	}
-	hg := BuildHypergraph(bufio.NewReader(stdout))
-	if err := cmd.Wait(); err != nil {
+	stopWatching := watchProcess(ctx, cmd, 0)
+	err := cmd.Wait()
+	if killed, _ := stopWatching(); killed {
+		return nil, ctx.Err()
+	}
+	if err != nil {
 		panic(fmt.Sprintf("hgtools failed: %v: %s", err, stderr.String()))
 	}
-	return hg
+	return BuildHypergraph(bufio.NewReader(&stdout)), nil
 }
@@ -1,6 +1,8 @@
 package decomp
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
@@ -29,38 +31,38 @@ func init() {
 	}
 }
 
-// Decompose the hypergraph of a CSP
-func Decompose(hgPath string, timeout string) string {
-	// TODO add logging
-	out, err := exec.Command(balancedGo, "-graph", hgPath, "-approx", timeout, "-det", "-bench").Output()
-	if err != nil {
-		if ee, ok := err.(*exec.ExitError); ok {
-			panic(fmt.Sprintf("BalancedGo failed: %v: %s", err, ee.Stderr))
-		} else {
-			panic(fmt.Sprintf("BalancedGo failed: %v", err))
-		}
-	}
-	res := string(out)
-	if strings.HasSuffix(res, "false\n") {
-		return ""
-	}
-	return res
+// Decompose the hypergraph of a CSP. BalancedGo is killed when ctx is done.
+func Decompose(ctx context.Context, hgPath string, timeout string) (string, error) {
+	return runBalancedGo(ctx, "-graph", hgPath, "-approx", timeout, "-det", "-bench")
 }
 
 // DecomposeToFile decompose a hypergraph and saves the decomposition on a file
-func DecomposeToFile(hgPath string, htPath string, timeout string) string {
+func DecomposeToFile(ctx context.Context, hgPath string, htPath string, timeout string) (string, error) {
+	return runBalancedGo(ctx, "-graph", hgPath, "-approx", timeout, "-det", "-gml", htPath, "-bench")
+}
+
+// runBalancedGo returns the decomposition printed by BalancedGo, or "" if it found none
+func runBalancedGo(ctx context.Context, args ...string) (string, error) {
 	// TODO add logging
-	out, err := exec.Command(balancedGo, "-graph", hgPath, "-approx", timeout, "-det", "-gml", htPath, "-bench").Output()
+	cmd := exec.Command(balancedGo, args...)
+	setProcessGroup(cmd)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		panic(fmt.Sprintf("BalancedGo failed: %v", err))
+	}
+	stopWatching := watchProcess(ctx, cmd, 0)
+	err := cmd.Wait()
+	if killed, _ := stopWatching(); killed {
+		return "", ctx.Err()
+	}
 	if err != nil {
-		if ee, ok := err.(*exec.ExitError); ok {
-			panic(fmt.Sprintf("BalancedGo failed: %v: %s", err, ee.Stderr))
-		} else {
-			panic(fmt.Sprintf("BalancedGo failed: %v", err))
-		}
+		panic(fmt.Sprintf("BalancedGo failed: %v: %s", err, stderr.String()))
 	}
-	res := string(out)
+	res := stdout.String()
 	if strings.HasSuffix(res, "false\n") {
-		return ""
+		return "", nil
 	}
-	return res
+	return res, nil
 }
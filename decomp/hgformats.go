@@ -0,0 +1,354 @@
+package decomp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dmlongo/callidus/files"
+)
+
+// Name of a hyperedge
+func (e Edge) Name() string {
+	return e.name
+}
+
+// Vertices of a hyperedge
+func (e Edge) Vertices() []string {
+	return e.vertices
+}
+
+// Edges of a hypergraph sorted by name
+func (hg Hypergraph) Edges() []Edge {
+	names := make([]string, 0, len(hg))
+	for name := range hg {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	edges := make([]Edge, 0, len(hg))
+	for _, name := range names {
+		edges = append(edges, hg[name])
+	}
+	return edges
+}
+
+// Vertices of a hypergraph sorted by name
+func (hg Hypergraph) Vertices() []string {
+	seen := make(map[string]bool)
+	var vertices []string
+	for _, e := range hg {
+		for _, v := range e.vertices {
+			if !seen[v] {
+				seen[v] = true
+				vertices = append(vertices, v)
+			}
+		}
+	}
+	sort.Strings(vertices)
+	return vertices
+}
+
+// ReadHypergraph from a file in the format given by its extension
+// (.hgr for PACE, .json for JSON, HyperBench otherwise)
+func ReadHypergraph(hgPath string) Hypergraph {
+	file, err := os.Open(hgPath)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	switch filepath.Ext(hgPath) {
+	case ".hgr":
+		return ParseHGR(bufio.NewReader(file))
+	case ".json":
+		return ParseHypergraphJSON(file)
+	default:
+		return ParseHyperBench(file)
+	}
+}
+
+// WriteToFile a hypergraph in the format given by the extension of hgPath
+// (.hgr for PACE, .json for JSON, HyperBench otherwise)
+func (hg Hypergraph) WriteToFile(hgPath string) {
+	file, err := os.Create(hgPath)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	switch filepath.Ext(hgPath) {
+	case ".hgr":
+		hg.WriteHGR(file)
+	case ".json":
+		hg.WriteJSON(file)
+	default:
+		hg.WriteHyperBench(file)
+	}
+}
+
+// ParseHyperBench parses a hypergraph in the HyperBench format, i.e.,
+// edges name(v1,v2,...) separated by commas and terminated by a dot.
+// Names can be quoted, and lines starting with % are comments.
+func ParseHyperBench(r io.Reader) Hypergraph {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		panic(err)
+	}
+	p := &hbParser{in: []rune(string(content))}
+	hg := make(Hypergraph)
+	for p.skip(); p.pos < len(p.in); p.skip() {
+		name := p.name()
+		p.expect('(')
+		var vertices []string
+		for {
+			vertices = append(vertices, p.name())
+			if p.next() == ')' {
+				break
+			}
+			p.pos--
+			p.expect(',')
+		}
+		hg.AddEdge(name, vertices)
+		if c := p.next(); c == '.' {
+			break
+		} else if c != ',' {
+			p.fail("expected , or .")
+		}
+	}
+	return hg
+}
+
+type hbParser struct {
+	in  []rune
+	pos int
+}
+
+func (p *hbParser) skip() {
+	for p.pos < len(p.in) {
+		c := p.in[p.pos]
+		if c == '%' {
+			for p.pos < len(p.in) && p.in[p.pos] != '\n' {
+				p.pos++
+			}
+		} else if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			p.pos++
+		} else {
+			return
+		}
+	}
+}
+
+func (p *hbParser) next() rune {
+	p.skip()
+	if p.pos >= len(p.in) {
+		p.fail("unexpected end of input")
+	}
+	c := p.in[p.pos]
+	p.pos++
+	return c
+}
+
+func (p *hbParser) expect(c rune) {
+	if p.next() != c {
+		p.fail("expected " + string(c))
+	}
+}
+
+func (p *hbParser) name() string {
+	p.skip()
+	if p.pos < len(p.in) && p.in[p.pos] == '"' {
+		p.pos++
+		var sb strings.Builder
+		for ; p.pos < len(p.in) && p.in[p.pos] != '"'; p.pos++ {
+			if p.in[p.pos] == '\\' && p.pos+1 < len(p.in) {
+				p.pos++
+			}
+			sb.WriteRune(p.in[p.pos])
+		}
+		if p.pos >= len(p.in) {
+			p.fail("unterminated name")
+		}
+		p.pos++
+		return sb.String()
+	}
+	start := p.pos
+	for p.pos < len(p.in) && !strings.ContainsRune("(),\"% \t\r\n", p.in[p.pos]) {
+		p.pos++
+	}
+	if start == p.pos {
+		p.fail("expected a name")
+	}
+	return string(p.in[start:p.pos])
+}
+
+func (p *hbParser) fail(msg string) {
+	line := 1 + strings.Count(string(p.in[:p.pos]), "\n")
+	panic(fmt.Sprintf("Bad hypergraph, line %v: %s", line, msg))
+}
+
+var plainName = regexp.MustCompile(`^\w+$`)
+
+func quoteName(name string) string {
+	if plainName.MatchString(name) {
+		return name
+	}
+	return "\"" + strings.ReplaceAll(strings.ReplaceAll(name, "\\", "\\\\"), "\"", "\\\"") + "\""
+}
+
+// WriteHyperBench writes a hypergraph in the HyperBench format
+func (hg Hypergraph) WriteHyperBench(out io.Writer) {
+	w := bufio.NewWriter(out)
+	edges := hg.Edges()
+	for i, e := range edges {
+		var sb strings.Builder
+		sb.WriteString(quoteName(e.name))
+		sb.WriteByte('(')
+		for j, v := range e.vertices {
+			if j > 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(quoteName(v))
+		}
+		sb.WriteByte(')')
+		if i < len(edges)-1 {
+			sb.WriteByte(',')
+		} else {
+			sb.WriteByte('.')
+		}
+		writeLine(w, sb.String())
+	}
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
+}
+
+// ParseHGR parses a hypergraph in the PACE .hgr format.
+// Comment lines "c v <number> <name>" and "c e <number> <name>" give the
+// names of vertices and edges, otherwise their numbers are used as names.
+func ParseHGR(r *bufio.Reader) Hypergraph {
+	vNames := make(map[string]string)
+	eNames := make(map[string]string)
+	type rawEdge struct {
+		id       string
+		vertices []string
+	}
+	var edges []rawEdge
+	numLines := 0
+	for {
+		line, eof := files.ReadLineCount(r, &numLines)
+		if eof {
+			break
+		}
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "p ") {
+			continue
+		}
+		if line == "c" || strings.HasPrefix(line, "c ") {
+			if res := regexPACEName.FindStringSubmatch(line); res != nil {
+				if res[1] == "v" {
+					vNames[res[2]] = res[3]
+				} else {
+					eNames[res[2]] = res[3]
+				}
+			}
+			continue
+		}
+		tks := strings.Fields(line)
+		if len(tks) < 2 {
+			panic("Bad edge in line " + strconv.Itoa(numLines) + ": " + line)
+		}
+		edges = append(edges, rawEdge{tks[0], tks[1:]})
+	}
+
+	hg := make(Hypergraph)
+	for _, e := range edges {
+		name := e.id
+		if n, ok := eNames[e.id]; ok {
+			name = n
+		}
+		hg.AddEdge(name, renamePACE(e.vertices, vNames))
+	}
+	return hg
+}
+
+// WriteHGR writes a hypergraph in the PACE .hgr format.
+// Vertices and edges are numbered following their lexicographic order.
+func (hg Hypergraph) WriteHGR(out io.Writer) {
+	w := bufio.NewWriter(out)
+	vertices := hg.Vertices()
+	vertexIdx := make(map[string]int)
+	for i, v := range vertices {
+		vertexIdx[v] = i + 1
+	}
+	edges := hg.Edges()
+
+	writeLine(w, fmt.Sprintf("p htd %v %v", len(vertices), len(edges)))
+	for i, v := range vertices {
+		writeLine(w, fmt.Sprintf("c v %v %s", i+1, v))
+	}
+	for i, e := range edges {
+		writeLine(w, fmt.Sprintf("c e %v %s", i+1, e.name))
+	}
+	for i, e := range edges {
+		var sb strings.Builder
+		sb.WriteString(strconv.Itoa(i + 1))
+		for _, v := range e.vertices {
+			sb.WriteString(" " + strconv.Itoa(vertexIdx[v]))
+		}
+		writeLine(w, sb.String())
+	}
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
+}
+
+type jsonEdge struct {
+	Name     string   `json:"name"`
+	Vertices []string `json:"vertices"`
+}
+
+type jsonHypergraph struct {
+	Edges []jsonEdge `json:"edges"`
+}
+
+// ParseHypergraphJSON parses a hypergraph in JSON format
+func ParseHypergraphJSON(r io.Reader) Hypergraph {
+	var raw jsonHypergraph
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		panic(err)
+	}
+	hg := make(Hypergraph)
+	for _, e := range raw.Edges {
+		hg.AddEdge(e.Name, e.Vertices)
+	}
+	return hg
+}
+
+// WriteJSON writes a hypergraph in JSON format
+func (hg Hypergraph) WriteJSON(out io.Writer) {
+	raw := jsonHypergraph{Edges: make([]jsonEdge, 0, len(hg))}
+	for _, e := range hg.Edges() {
+		raw.Edges = append(raw.Edges, jsonEdge{Name: e.name, Vertices: e.vertices})
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(raw); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,68 @@
+package decomp
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func hgEquals(hg1 Hypergraph, hg2 Hypergraph) bool {
+	if len(hg1) != len(hg2) {
+		return false
+	}
+	for name, e1 := range hg1 {
+		e2, ok := hg2[name]
+		if !ok || strings.Join(e1.vertices, ",") != strings.Join(e2.vertices, ",") {
+			return false
+		}
+	}
+	return true
+}
+
+func formatsTestHypergraph() Hypergraph {
+	hg := make(Hypergraph)
+	hg.AddEdge("e1", []string{"a", "b", "c"})
+	hg.AddEdge("e-2", []string{"c", "x[1]"})
+	hg.AddEdge("e3", []string{"a", "say \"hi\""})
+	return hg
+}
+
+func TestParseHyperBench(t *testing.T) {
+	in := `% a comment
+e1(a, b,c),
+"e-2"(c,"x[1]"),
+e3 (a,"say \"hi\"").
+`
+	hg := ParseHyperBench(strings.NewReader(in))
+	if !hgEquals(hg, formatsTestHypergraph()) {
+		t.Errorf("hg = %v, expected %v", hg, formatsTestHypergraph())
+	}
+}
+
+func TestHyperBenchRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	formatsTestHypergraph().WriteHyperBench(&buf)
+	if hg := ParseHyperBench(&buf); !hgEquals(hg, formatsTestHypergraph()) {
+		t.Errorf("hg = %v, expected %v", hg, formatsTestHypergraph())
+	}
+}
+
+func TestHGRRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	formatsTestHypergraph().WriteHGR(&buf)
+	if !strings.HasPrefix(buf.String(), "p htd 5 3\n") {
+		t.Errorf("bad header in\n%s", buf.String())
+	}
+	if hg := ParseHGR(bufio.NewReader(&buf)); !hgEquals(hg, formatsTestHypergraph()) {
+		t.Errorf("hg = %v, expected %v", hg, formatsTestHypergraph())
+	}
+}
+
+func TestJSONRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	formatsTestHypergraph().WriteJSON(&buf)
+	if hg := ParseHypergraphJSON(&buf); !hgEquals(hg, formatsTestHypergraph()) {
+		t.Errorf("hg = %v, expected %v", hg, formatsTestHypergraph())
+	}
+}
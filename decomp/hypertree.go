@@ -2,6 +2,7 @@ package decomp
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"sync"
 
@@ -28,11 +29,19 @@ type Node struct {
 	Lock  *sync.Mutex
 }
 
+// DistinctTables turns on set semantics in the tables of new nodes, so that
+// duplicates are dropped while sub-CSPs are solved, at the cost of a hash
+// index per table. Otherwise, duplicates are removed once a table is complete.
+var DistinctTables = false
+
 func NewNode(id int, vars []string, edges []string) *Node {
 	n := Node{ID: id}
 	n.SetBag(vars)
 	n.SetCover(edges)
 	n.Table = db.NewRelation(vars)
+	if n.Table != nil && DistinctTables {
+		n.Table.SetDistinct(true)
+	}
 	n.Lock = &sync.Mutex{}
 	return &n
 }
@@ -79,9 +88,16 @@ func (n *Node) Cover() []string {
 	return n.cover
 }
 
-// Complete a hypertree wrt a hypergraph
+// Complete a hypertree wrt a hypergraph. Nodes without a cover (e.g., from
+// tree decompositions) get one, and edges that are not in the cover of a
+// node containing them are attached to the tree.
 func (tree *Hypertree) Complete(hg Hypergraph) {
-	labels, maxID := tree.coveredEdges()
+	for _, n := range *tree {
+		if len(n.cover) == 0 && len(n.bag) > 0 {
+			n.SetCover(edgeCover(n.bag, hg))
+		}
+	}
+	labels, maxID := tree.coveredEdges(hg)
 	for k, e := range hg {
 		if _, ok := labels[k]; !ok {
 			tree.attach(e, &maxID)
@@ -89,12 +105,52 @@ func (tree *Hypertree) Complete(hg Hypergraph) {
 	}
 }
 
-func (tree *Hypertree) coveredEdges() (map[string]bool, int) {
+// edgeCover of a bag, choosing greedily the edges with most uncovered vertices
+func edgeCover(bag []string, hg Hypergraph) []string {
+	names := make([]string, 0, len(hg))
+	for name := range hg {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	uncovered := make(map[string]bool)
+	for _, v := range bag {
+		uncovered[v] = true
+	}
+	var cover []string
+	for len(uncovered) > 0 {
+		best, bestCount := "", 0
+		for _, name := range names {
+			count := 0
+			for _, v := range hg[name].vertices {
+				if uncovered[v] {
+					count++
+				}
+			}
+			if count > bestCount {
+				best, bestCount = name, count
+			}
+		}
+		if bestCount == 0 {
+			panic(fmt.Sprint("Could not cover bag ", bag))
+		}
+		cover = append(cover, best)
+		for _, v := range hg[best].vertices {
+			delete(uncovered, v)
+		}
+	}
+	return cover
+}
+
+// coveredEdges are the edges in the cover of some node whose bag contains them
+func (tree *Hypertree) coveredEdges(hg Hypergraph) (map[string]bool, int) {
 	res := make(map[string]bool)
 	maxID := 0
 	for _, n := range *tree {
 		for _, e := range n.cover {
-			res[e] = true
+			if subset(hg[e].vertices, n.bagSet) {
+				res[e] = true
+			}
 		}
 		if n.ID > maxID {
 			maxID = n.ID
@@ -150,3 +206,247 @@ func Bfs(root *Node) Hypertree {
 	}
 	return nodes
 }
+
+// Root of a hypertree, i.e., the only node without a parent
+func (tree Hypertree) Root() *Node {
+	for _, n := range tree {
+		if n.Parent == nil {
+			return n
+		}
+	}
+	return nil
+}
+
+// Height of the subtree rooted at n
+func Height(n *Node) int {
+	h := 0
+	for _, c := range n.Children {
+		if hc := Height(c) + 1; hc > h {
+			h = hc
+		}
+	}
+	return h
+}
+
+// Center of a hypertree, i.e., the node minimizing the height of the tree
+func (tree Hypertree) Center() *Node {
+	return tree.BestRoot(func(n *Node) int { return 1 })
+}
+
+// BestRoot of a hypertree wrt the cost of its nodes.
+// It returns the node minimizing the most expensive path to a leaf,
+// where the cost of a path is the sum of the costs of its nodes.
+func (tree Hypertree) BestRoot(cost func(*Node) int) *Node {
+	root := tree.Root()
+	if root == nil {
+		return nil
+	}
+	nodes := Bfs(root)
+
+	// down[n] is the most expensive path from n into its subtree
+	down := make(map[*Node]int, len(nodes))
+	for i := len(nodes) - 1; i >= 0; i-- {
+		n := nodes[i]
+		best := 0
+		for _, c := range n.Children {
+			if down[c] > best {
+				best = down[c]
+			}
+		}
+		down[n] = cost(n) + best
+	}
+
+	// up[n] is the most expensive path from the parent of n avoiding n
+	up := make(map[*Node]int, len(nodes))
+	var res *Node
+	resCost := 0
+	for _, n := range nodes {
+		first, second := up[n], 0
+		for _, c := range n.Children {
+			if down[c] > first {
+				first, second = down[c], first
+			} else if down[c] > second {
+				second = down[c]
+			}
+		}
+		if ecc := cost(n) + first; res == nil || ecc < resCost {
+			res, resCost = n, ecc
+		}
+		for _, c := range n.Children {
+			if down[c] == first {
+				up[c] = cost(n) + second
+			} else {
+				up[c] = cost(n) + first
+			}
+		}
+	}
+	return res
+}
+
+// TableCost estimates the cost of a node with the size of its table
+func TableCost(n *Node) int {
+	if n.Table == nil {
+		return 1
+	}
+	return n.Table.Size() + 1
+}
+
+// Reroot a hypertree in the given node
+func (tree Hypertree) Reroot(newRoot *Node) {
+	var path []*Node
+	for n := newRoot; n != nil; n = n.Parent {
+		path = append(path, n)
+	}
+	for i := 1; i < len(path); i++ {
+		path[i].removeChild(path[i-1])
+	}
+	newRoot.Parent = nil
+	for i := 1; i < len(path); i++ {
+		path[i-1].AddChild(path[i])
+	}
+}
+
+func (n *Node) removeChild(child *Node) {
+	for i, c := range n.Children {
+		if c == child {
+			n.Children = append(n.Children[:i], n.Children[i+1:]...)
+			child.Parent = nil
+			return
+		}
+	}
+}
+
+// Normalize a hypertree by merging every node whose bag is subsumed by
+// the bag of a neighbour into that neighbour. IDs are then reassigned
+// contiguously in BFS order. It returns the (possibly new) root, or nil
+// if the tree is empty.
+func (tree *Hypertree) Normalize() *Node {
+	root := tree.Root()
+	if root == nil {
+		return nil
+	}
+	for changed := true; changed; {
+		changed = false
+		for _, n := range Bfs(root) {
+			p := n.Parent
+			if p == nil {
+				continue
+			}
+			if subset(n.bag, p.bagSet) {
+				p.absorb(n)
+			} else if subset(p.bag, n.bagSet) {
+				n.absorb(p)
+				if p == root {
+					root = n
+				}
+			} else {
+				continue
+			}
+			changed = true
+			break
+		}
+	}
+
+	*tree = Bfs(root)
+	for i, n := range *tree {
+		n.ID = i
+	}
+	return root
+}
+
+// absorb an adjacent node into this node
+func (n *Node) absorb(m *Node) {
+	cover := n.cover
+	for _, e := range m.cover {
+		if !n.coverSet[e] {
+			cover = append(cover, e)
+		}
+	}
+	n.SetCover(cover)
+
+	if m.Parent == n {
+		n.removeChild(m)
+	} else {
+		m.removeChild(n)
+		if gp := m.Parent; gp != nil {
+			for i, c := range gp.Children {
+				if c == m {
+					gp.Children[i] = n
+				}
+			}
+		}
+		n.Parent = m.Parent
+	}
+	for _, c := range m.Children {
+		n.AddChild(c)
+	}
+	m.Children = nil
+	m.Parent = nil
+}
+
+// Validate a hypertree wrt a hypergraph. Every edge must be covered by
+// some bag, every bag by the edges of its node, the nodes containing a
+// vertex must be connected and the tree must be consistent.
+func (tree Hypertree) Validate(hg Hypergraph) error {
+	root := tree.Root()
+	if root == nil {
+		return fmt.Errorf("hypertree has no root")
+	}
+	nodes := Bfs(root)
+	if len(nodes) != len(tree) {
+		return fmt.Errorf("hypertree has %v nodes, but only %v are reachable from the root", len(tree), len(nodes))
+	}
+	ids := make(map[int]bool)
+	for _, n := range nodes {
+		if ids[n.ID] {
+			return fmt.Errorf("duplicate node ID %v", n.ID)
+		}
+		ids[n.ID] = true
+		for _, c := range n.Children {
+			if c.Parent != n {
+				return fmt.Errorf("node %v is a child of %v, but its parent is not", c.ID, n.ID)
+			}
+		}
+		coverVars := make(map[string]int)
+		for _, e := range n.cover {
+			if _, ok := hg[e]; !ok {
+				return fmt.Errorf("node %v covers unknown edge %v", n.ID, e)
+			}
+			for _, v := range hg[e].vertices {
+				coverVars[v] = 0
+			}
+		}
+		if !subset(n.bag, coverVars) {
+			return fmt.Errorf("bag of node %v is not covered by %v", n.ID, n.cover)
+		}
+	}
+
+	for name, e := range hg {
+		covered := false
+		for _, n := range nodes {
+			if subset(e.vertices, n.bagSet) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return fmt.Errorf("edge %v is not covered by any bag", name)
+		}
+	}
+
+	// a vertex is connected iff exactly one of its nodes has a parent without it
+	tops := make(map[string]int)
+	for _, n := range nodes {
+		for _, v := range n.bag {
+			if n.Parent == nil || n.Parent.Position(v) < 0 {
+				tops[v]++
+			}
+		}
+	}
+	for v, k := range tops {
+		if k > 1 {
+			return fmt.Errorf("vertex %v is not connected", v)
+		}
+	}
+	return nil
+}
@@ -1,7 +1,10 @@
 package decomp
 
 import (
+	"encoding/json"
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -47,3 +50,162 @@ func print(res []int, exp []int) string {
 	sb.WriteString(fmt.Sprintf("%v", exp))
 	return sb.String()
 }
+
+func TestCenter(t *testing.T) {
+	n1 := &Node{ID: 1}
+	n2 := &Node{ID: 2}
+	n3 := &Node{ID: 3}
+	n4 := &Node{ID: 4}
+	n5 := &Node{ID: 5}
+	n1.AddChild(n2)
+	n2.AddChild(n3)
+	n3.AddChild(n4)
+	n3.AddChild(n5)
+	tree := Hypertree{n1, n2, n3, n4, n5}
+
+	if c := tree.Center(); c != n3 && c != n2 {
+		t.Errorf("center = %v, expected 2 or 3", c.ID)
+	}
+
+	cost := map[int]int{1: 1, 2: 1, 3: 1, 4: 10, 5: 1}
+	if c := tree.BestRoot(func(n *Node) int { return cost[n.ID] }); c != n3 {
+		t.Errorf("best root = %v, expected 3", c.ID)
+	}
+}
+
+func TestReroot(t *testing.T) {
+	n1 := &Node{ID: 1}
+	n2 := &Node{ID: 2}
+	n3 := &Node{ID: 3}
+	n4 := &Node{ID: 4}
+	n5 := &Node{ID: 5}
+	n1.AddChild(n2)
+	n2.AddChild(n3)
+	n2.AddChild(n4)
+	n4.AddChild(n5)
+	tree := Hypertree{n1, n2, n3, n4, n5}
+
+	tree.Reroot(n4)
+	if tree.Root() != n4 || n4.Parent != nil {
+		t.Errorf("root = %v, expected 4", tree.Root().ID)
+	}
+	if h := Height(n4); h != 2 {
+		t.Errorf("height = %v, expected 2", h)
+	}
+
+	expected := []int{4, 5, 2, 3, 1}
+	var result []int
+	for _, n := range Bfs(n4) {
+		result = append(result, n.ID)
+	}
+	if len(result) != len(expected) {
+		t.Fatalf("%s\nlen(result) = %v, len(expected) = %v", print(result, expected), len(result), len(expected))
+	}
+	for i := range result {
+		if result[i] != expected[i] {
+			t.Errorf("%s\nresult[%v] = %v, expected[%v] = %v", print(result, expected), i, result[i], i, expected[i])
+		}
+	}
+	for _, n := range tree {
+		for _, c := range n.Children {
+			if c.Parent != n {
+				t.Errorf("parent of %v = %v, expected %v", c.ID, c.Parent.ID, n.ID)
+			}
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	hg := make(Hypergraph)
+	hg.AddEdge("e1", []string{"a", "b", "c"})
+	hg.AddEdge("e2", []string{"c", "d"})
+	hg.AddEdge("e3", []string{"a", "b"})
+	hg.AddEdge("e4", []string{"c", "d", "e"})
+	hg.AddEdge("e5", []string{"e", "f"})
+
+	n1 := NewNode(1, []string{"c", "d"}, []string{"e2"})
+	n2 := NewNode(2, []string{"a", "b", "c"}, []string{"e1"})
+	n3 := NewNode(3, []string{"c", "d", "e"}, []string{"e4"})
+	n4 := NewNode(4, []string{"e", "f"}, []string{"e5"})
+	n1.AddChild(n2)
+	n1.AddChild(n3)
+	n3.AddChild(n4)
+	tree := Hypertree{n1, n2, n3, n4}
+	tree.Complete(hg)
+	if len(tree) != 5 {
+		t.Fatalf("len(tree) = %v, expected 5", len(tree))
+	}
+
+	root := tree.Normalize()
+	if len(tree) != 3 {
+		t.Errorf("len(tree) = %v, expected 3", len(tree))
+	}
+	if root != n3 {
+		t.Errorf("root = %v, expected node with bag %v", root.Bag(), n3.Bag())
+	}
+	if len(n2.Cover()) != 2 || len(n3.Cover()) != 2 {
+		t.Errorf("covers = %v, %v, expected two edges each", n2.Cover(), n3.Cover())
+	}
+	for i, n := range tree {
+		if n.ID != i {
+			t.Errorf("tree[%v].ID = %v", i, n.ID)
+		}
+	}
+	if err := tree.Validate(hg); err != nil {
+		t.Error(err)
+	}
+
+	var empty Hypertree
+	if root := empty.Normalize(); root != nil || len(empty) != 0 {
+		t.Errorf("empty tree normalized to %v, %v", root, empty)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	hg := make(Hypergraph)
+	hg.AddEdge("e1", []string{"a", "b"})
+	hg.AddEdge("e2", []string{"b", "c"})
+	hg.AddEdge("e3", []string{"c", "a"})
+
+	n1 := NewNode(1, []string{"a", "b"}, []string{"e1"})
+	n2 := NewNode(2, []string{"b", "c"}, []string{"e2"})
+	n3 := NewNode(3, []string{"c", "a"}, []string{"e3"})
+	n1.AddChild(n2)
+	n2.AddChild(n3)
+	tree := Hypertree{n1, n2, n3}
+
+	if err := tree.Validate(hg); err == nil {
+		t.Error("a is not connected, but tree is valid")
+	}
+}
+
+func TestStats(t *testing.T) {
+	hg := make(Hypergraph)
+	hg.AddEdge("e1", []string{"a", "b", "c"})
+	hg.AddEdge("e2", []string{"c", "d"})
+	hs := hg.Stats()
+	if hs.Vertices != 4 || hs.Edges != 2 || hs.MaxEdgeSize != 3 || hs.AvgEdgeSize != 2.5 || hs.MaxDegree != 2 || hs.Degrees[1] != 3 {
+		t.Errorf("hypergraph stats = %+v", hs)
+	}
+
+	n1 := NewNode(1, []string{"a", "b", "c"}, []string{"e1"})
+	n2 := NewNode(2, []string{"c", "d"}, []string{"e2"})
+	n1.AddChild(n2)
+	doms := map[string]string{"a": "0..1", "b": "0..1", "c": "1 2 3", "d": "0..9"}
+	ts := Hypertree{n1, n2}.Stats(doms)
+	if ts.Nodes != 2 || ts.Width != 1 || ts.Depth != 1 || ts.MaxBagSize != 3 || ts.MaxTableSize != 30 || ts.SumTableSize != 42 {
+		t.Errorf("hypertree stats = %+v", ts)
+	}
+
+	var bag []string
+	big := make(map[string]string)
+	for i := 0; i < 40; i++ {
+		v := "x" + strconv.Itoa(i)
+		bag = append(bag, v)
+		big[v] = "0..1000000000"
+	}
+	ts = Hypertree{NewNode(1, bag, nil)}.Stats(big)
+	if _, err := json.Marshal(ts); err != nil || ts.MaxTableSize != math.MaxFloat64 {
+		t.Errorf("huge tables: %+v, %v", ts, err)
+	}
+}
@@ -0,0 +1,185 @@
+package decomp
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// SubOptions are the solver of the sub-CSPs, its limits and the folder where
+// their tables are cached. Zero values mean nacre, no limit and no cache.
+type SubOptions struct {
+	Solver      Solver
+	CacheDir    string
+	KeepFiles   bool          // write the sub-CSPs into files, even if the solver reads them from stdin
+	NodeTimeout time.Duration // wall-clock time of the solver of a single node
+	Timeout     time.Duration // wall-clock time of solving all nodes
+	NodeMemory  uint64        // resident memory of the solver of a single node and its children, in bytes
+}
+
+func (opts SubOptions) solver() Solver {
+	if opts.Solver == nil {
+		s, _ := NewSolver("nacre", "", "")
+		return s
+	}
+	return opts.Solver
+}
+
+// memoryPoll is how often the memory of the solvers is checked
+var memoryPoll = 100 * time.Millisecond
+
+// LimitError is returned when a solver is stopped because it exceeded a limit.
+// Node is -1 if the limit is on the solving of all nodes.
+type LimitError struct {
+	Node  int
+	Limit string
+}
+
+func (e *LimitError) Error() string {
+	if e.Node < 0 {
+		return fmt.Sprintf("%s in sub-CSPs", e.Limit)
+	}
+	return fmt.Sprintf("%s in node %v", e.Limit, e.Node)
+}
+
+// withTimeout is context.WithTimeout, or context.WithCancel if there is no timeout
+func withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+type outerKey struct{}
+type limitKey struct{}
+
+// withLimit is withTimeout, but it remembers ctx and the new context so
+// that limitError can tell which one was stopped
+func withLimit(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	limited, cancel := withTimeout(context.WithValue(ctx, outerKey{}, ctx), timeout)
+	return context.WithValue(limited, limitKey{}, limited), cancel
+}
+
+// limitError explains why the solving of a node stopped, if ctx is done.
+// The expiration of the timeout given to withLimit is a LimitError for all
+// nodes (-1), the one of a node timeout is a LimitError for node, and
+// otherwise the error of the context given to withLimit is returned.
+func limitError(ctx context.Context, node int) error {
+	if ctx.Err() == nil {
+		return nil
+	}
+	if outer, ok := ctx.Value(outerKey{}).(context.Context); ok && outer.Err() != nil {
+		return outer.Err()
+	}
+	if limited, ok := ctx.Value(limitKey{}).(context.Context); ok && limited.Err() == context.DeadlineExceeded {
+		return &LimitError{Node: -1, Limit: "timeout"}
+	}
+	if ctx.Err() == context.DeadlineExceeded {
+		return &LimitError{Node: node, Limit: "timeout"}
+	}
+	return ctx.Err()
+}
+
+// runSolver runs the solver on cspFile, or on instance through its standard
+// input if cspFile is empty, and adds its solutions to the table of node.
+// The solver and all of its children are killed when ctx is done, or when
+// they exceed the limits in opts.
+func runSolver(ctx context.Context, cspFile string, instance []byte, node *Node, opts SubOptions) (bool, error) {
+	ctx, cancel := withTimeout(ctx, opts.NodeTimeout)
+	defer cancel()
+	if err := ctx.Err(); err != nil {
+		return false, limitError(ctx, node.ID)
+	}
+
+	solver := opts.solver()
+	cmd := solver.Command(cspFile)
+	if cspFile == "" {
+		cmd.Stdin = bytes.NewReader(instance)
+		cspFile = "sub" + strconv.Itoa(node.ID) // for errors
+	}
+	setProcessGroup(cmd)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		panic(err)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		panic(err)
+	}
+
+	stopWatching := watchProcess(ctx, cmd, opts.NodeMemory)
+	res := false
+	readErr := solver.ReadSolutions(stdout, func(vars []string, vals []int) {
+		tup := makeTuple(vars, vals, node.bagSet)
+		if t, _ := node.Table.AddTuple(tup); t == nil {
+			panic(fmt.Sprintf("node %v, %s: Tuple arity does not match with relation arity %v", node.ID, cspFile, len(node.Table.Attributes())))
+		}
+		res = true
+	})
+	if readErr != nil {
+		io.Copy(ioutil.Discard, stdout) // a killed solver may leave a truncated output
+	}
+	err = cmd.Wait()
+	killed, outOfMemory := stopWatching()
+
+	if outOfMemory {
+		return false, &LimitError{Node: node.ID, Limit: "memory limit"}
+	}
+	if killed {
+		return false, limitError(ctx, node.ID)
+	}
+	if readErr != nil {
+		panic(fmt.Sprintf("node %v, %s: %v", node.ID, cspFile, readErr))
+	}
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); !ok || !solver.Succeeded(ee.ExitCode()) {
+			panic(fmt.Sprintf("solver failed on %s: %v: %s", cspFile, err, stderr.String()))
+		}
+	}
+	return res, nil
+}
+
+// watchProcess kills the process group of a started command when ctx is
+// done, or when the command uses more than maxMemory bytes (if positive).
+// The returned function stops watching, after the command has exited, and
+// tells whether the command was killed and whether it ran out of memory.
+func watchProcess(ctx context.Context, cmd *exec.Cmd, maxMemory uint64) func() (killed bool, outOfMemory bool) {
+	var killed, outOfMemory bool
+	done := make(chan struct{})
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+		var poll <-chan time.Time
+		if maxMemory > 0 {
+			ticker := time.NewTicker(memoryPoll)
+			defer ticker.Stop()
+			poll = ticker.C
+		}
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+			case <-poll:
+				if mem, err := groupMemory(cmd.Process.Pid); err != nil || mem <= maxMemory {
+					continue
+				}
+				outOfMemory = true
+			}
+			killed = true
+			killProcessGroup(cmd)
+			return
+		}
+	}()
+	return func() (bool, bool) {
+		close(done)
+		<-watched
+		return killed, outOfMemory
+	}
+}
@@ -0,0 +1,115 @@
+package decomp
+
+import (
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// fakeSolver replaces the solver with a shell script for the duration of a test
+func fakeSolver(t *testing.T, script string) {
+	path := writeTestFile(t, "solver.sh", "#!/bin/sh\n"+script)
+	if err := os.Chmod(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+	old := nacre
+	nacre = path
+	t.Cleanup(func() { nacre = old })
+}
+
+func TestRunSolver(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell")
+	}
+	fakeSolver(t, `echo "v <instantiation> <list> x y </list> <values> 1 2 </values> </instantiation>"
+exit 40
+`)
+	n := NewNode(1, []string{"x"}, []string{})
+	sat, err := runSolver(context.Background(), "sub1.xml", nil, n, SubOptions{})
+	if err != nil || !sat || n.Table.Size() != 1 || n.Table.Value(0, 0) != 1 {
+		t.Errorf("runSolver = %v, %v, table %v", sat, err, n.Table.Tuples())
+	}
+}
+
+func TestRunSolverTimeout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a shell")
+	}
+	// the child keeps stdout open, so the solver is stopped only if its whole group is killed
+	fakeSolver(t, `echo "v <instantiation> <list> x </list> <values> 1 </values> </instantiation>"
+printf "v <instan"
+sleep 30
+`)
+	n := NewNode(3, []string{"x"}, []string{})
+	start := time.Now()
+	sat, err := runSolver(context.Background(), "sub3.xml", nil, n, SubOptions{NodeTimeout: 200 * time.Millisecond})
+	var limErr *LimitError
+	if sat || !errors.As(err, &limErr) || err.Error() != "timeout in node 3" {
+		t.Errorf("runSolver = %v, %v, expected timeout in node 3", sat, err)
+	}
+	if d := time.Since(start); d > 10*time.Second {
+		t.Errorf("solver stopped after %v", d)
+	}
+}
+
+func TestRunSolverStdin(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("needs /dev/stdin")
+	}
+	fakeSolver(t, `while read l; do echo "v <instantiation> <list> x </list> <values> ${l#x=} </values> </instantiation>"; done < "$1"
+exit 40
+`)
+	n := NewNode(1, []string{"x"}, []string{})
+	sat, err := runSolver(context.Background(), "", []byte("x=4\nx=7\n"), n, SubOptions{})
+	if err != nil || !sat || n.Table.Size() != 2 || n.Table.Value(1, 0) != 7 {
+		t.Errorf("runSolver = %v, %v, table %v", sat, err, n.Table.Tuples())
+	}
+}
+
+func TestLimitError(t *testing.T) {
+	outer, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	ctx, cancelLimit := withLimit(outer, time.Hour)
+	defer cancelLimit()
+	<-ctx.Done()
+	if err := limitError(ctx, 3); err != context.DeadlineExceeded {
+		t.Errorf("limitError = %v after the outer timeout, expected %v", err, context.DeadlineExceeded)
+	}
+
+	limited, cancelLimit := withLimit(context.Background(), time.Nanosecond)
+	defer cancelLimit()
+	<-limited.Done()
+	nodeLimited, cancelLimit := withLimit(context.Background(), time.Hour)
+	defer cancelLimit()
+	nodeLimited, cancelNode := withTimeout(nodeLimited, time.Nanosecond)
+	defer cancelNode()
+	<-nodeLimited.Done()
+	for ctx, exp := range map[context.Context]string{limited: "timeout in sub-CSPs", nodeLimited: "timeout in node 3"} {
+		var limErr *LimitError
+		if err := limitError(ctx, 3); !errors.As(err, &limErr) || err.Error() != exp {
+			t.Errorf("limitError = %v, expected %v", err, exp)
+		}
+	}
+}
+
+func TestSolveOuterTimeout(t *testing.T) {
+	ctrs, doms := slowSolverTest(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, tree := pipelineTestTree()
+	_, err := SolveSubCspSeq(ctx, tree, doms, ctrs, t.TempDir()+"/", SubOptions{Timeout: time.Hour})
+	var limErr *LimitError
+	if errors.As(err, &limErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SolveSubCspSeq = %v, expected %v", err, context.DeadlineExceeded)
+	}
+
+	_, tree = pipelineTestTree()
+	_, err = SolveSubCspSeq(context.Background(), tree, doms, ctrs, t.TempDir()+"/", SubOptions{NodeTimeout: 200 * time.Millisecond, Timeout: time.Hour})
+	if !errors.As(err, &limErr) || limErr.Node < 0 {
+		t.Errorf("SolveSubCspSeq = %v, expected a timeout in a node", err)
+	}
+}
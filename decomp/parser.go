@@ -2,6 +2,7 @@ package decomp
 
 import (
 	"bufio"
+	"io/ioutil"
 	"os"
 	"regexp"
 	"sort"
@@ -19,7 +20,44 @@ var regexEdgeTarget = regexp.MustCompile(`target (.*).*`)
 var regexBag = regexp.MustCompile(`Bag: {(.*)}.*`)
 var regexCover = regexp.MustCompile(`Cover: {(.*)}.*`)
 
-// ParseGML parses a decomposition file in GML format
+// ParseHypertree parses a decomposition file detecting its format (GML, PACE or BalancedGo).
+// The decompositions of det-k-decomp and NewDetKDecomp are read as GML, which they write.
+func ParseHypertree(htPath string) (*Node, []*Node) {
+	file, err := os.Open(htPath)
+	if err != nil {
+		panic(err)
+	}
+	reader := bufio.NewReader(file)
+	header := ""
+	for {
+		line, eof := files.ReadLine(reader)
+		if eof {
+			break
+		}
+		line = strings.TrimSpace(line)
+		if line != "" && line != "c" && !strings.HasPrefix(line, "c ") {
+			header = line
+			break
+		}
+	}
+	if err := file.Close(); err != nil {
+		panic(err)
+	}
+
+	switch {
+	case strings.HasPrefix(header, "s td") || strings.HasPrefix(header, "s htd"):
+		return ParsePACE(htPath)
+	case strings.HasPrefix(header, "graph") || strings.HasPrefix(header, "Graph"):
+		return ParseGML(htPath)
+	case strings.Contains(header, "Bag"):
+		return ParseBalancedGoFile(htPath)
+	default:
+		panic("Unknown decomposition format: " + htPath)
+	}
+}
+
+// ParseGML parses a decomposition file in GML format, where the label of a
+// node is its cover followed by its bag, as written by det-k-decomp
 func ParseGML(htPath string) (*Node, []*Node) {
 	file, err := os.Open(htPath)
 	if err != nil {
@@ -147,3 +185,145 @@ func ParseBalancedGo(htRaw *string) (*Node, []*Node) {
 	}
 	return root, onlyNodes
 }
+
+var regexPACEName = regexp.MustCompile(`^c ([ve]) (\d+) (.+)$`)
+
+// ParsePACE parses a decomposition file in the PACE .td or .htd format.
+// Comment lines "c v <number> <name>" and "c e <number> <name>" give the
+// names of vertices and edges, otherwise their numbers are used as names.
+// Bag 1 is the root of the decomposition.
+func ParsePACE(htPath string) (*Node, []*Node) {
+	file, err := os.Open(htPath)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	vNames := make(map[string]string)
+	eNames := make(map[string]string)
+	bags := make(map[int][]string)
+	covers := make(map[int][]string)
+	adj := make(map[int][]int)
+	numBags := -1
+	reader := bufio.NewReader(file)
+	numLines := 0
+	for {
+		line, eof := files.ReadLineCount(reader, &numLines)
+		if eof {
+			break
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		tks := strings.Fields(line)
+		switch tks[0] {
+		case "c":
+			if res := regexPACEName.FindStringSubmatch(line); res != nil {
+				if res[1] == "v" {
+					vNames[res[2]] = res[3]
+				} else {
+					eNames[res[2]] = res[3]
+				}
+			}
+		case "s":
+			if len(tks) < 3 {
+				panic("Cannot parse solution line " + strconv.Itoa(numLines) + ": " + line)
+			}
+			numBags = parsePACEInt(tks[2], numLines, line)
+		case "b":
+			if len(tks) < 2 {
+				panic("Cannot parse bag in line " + strconv.Itoa(numLines) + ": " + line)
+			}
+			id := parsePACEInt(tks[1], numLines, line)
+			bags[id] = append(bags[id], tks[2:]...)
+		case "w":
+			if len(tks) < 4 {
+				panic("Cannot parse weight in line " + strconv.Itoa(numLines) + ": " + line)
+			}
+			id := parsePACEInt(tks[1], numLines, line)
+			w, err := strconv.ParseFloat(tks[3], 64)
+			if err != nil {
+				panic("Cannot parse weight in line " + strconv.Itoa(numLines) + ": " + line)
+			}
+			if w > 0 {
+				covers[id] = append(covers[id], tks[2])
+			}
+		default:
+			if len(tks) != 2 {
+				panic("Cannot parse tree edge in line " + strconv.Itoa(numLines) + ": " + line)
+			}
+			a := parsePACEInt(tks[0], numLines, line)
+			b := parsePACEInt(tks[1], numLines, line)
+			adj[a] = append(adj[a], b)
+			adj[b] = append(adj[b], a)
+		}
+	}
+	if numBags < 0 {
+		panic("Cannot find solution line in " + htPath)
+	}
+	if numBags == 0 {
+		panic("Empty decomposition in " + htPath)
+	}
+
+	nodes := make(map[int]*Node)
+	var onlyNodes []*Node
+	for id := 1; id <= numBags; id++ {
+		variables := renamePACE(bags[id], vNames)
+		sort.Strings(variables)
+		node := NewNode(id, variables, renamePACE(covers[id], eNames))
+		nodes[id] = node
+		onlyNodes = append(onlyNodes, node)
+	}
+
+	root := nodes[1]
+	visited := map[int]bool{1: true}
+	toVisit := []int{1}
+	var curr int
+	for len(toVisit) > 0 {
+		curr, toVisit = toVisit[0], toVisit[1:]
+		for _, next := range adj[curr] {
+			if !visited[next] {
+				visited[next] = true
+				nodes[curr].AddChild(nodes[next])
+				toVisit = append(toVisit, next)
+			}
+		}
+	}
+	return root, onlyNodes
+}
+
+func parsePACEInt(s string, numLine int, line string) int {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		panic("Cannot parse number in line " + strconv.Itoa(numLine) + ": " + line)
+	}
+	return i
+}
+
+func renamePACE(ids []string, names map[string]string) []string {
+	res := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if name, ok := names[id]; ok {
+			res = append(res, name)
+		} else {
+			res = append(res, id)
+		}
+	}
+	return res
+}
+
+// ParseBalancedGoFile parses a decomposition file in the textual format
+// printed by BalancedGo, i.e., nested Bag, Cover and Children blocks
+func ParseBalancedGoFile(htPath string) (*Node, []*Node) {
+	content, err := ioutil.ReadFile(htPath)
+	if err != nil {
+		panic(err)
+	}
+	htRaw := string(content)
+	return ParseBalancedGo(&htRaw)
+}
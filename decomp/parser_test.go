@@ -0,0 +1,175 @@
+package decomp
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParsePACEHtd(t *testing.T) {
+	htPath := writeTestFile(t, "tree.htd", `c an htd file
+s htd 3 1 5 3
+c v 1 a
+c v 2 b
+c e 1 e1
+b 1 1 2 3
+b 2 3 4
+b 3 1 5
+w 1 1 1
+w 1 2 0
+w 2 2 1
+w 3 3 1
+2 1
+1 3
+`)
+	root, tree := ParseHypertree(htPath)
+	if root == nil || root.ID != 1 || len(tree) != 3 {
+		t.Fatalf("root = %v, len(tree) = %v", root, len(tree))
+	}
+	if bag := strings.Join(root.Bag(), ","); bag != "3,a,b" {
+		t.Errorf("root bag = %v, expected 3,a,b", bag)
+	}
+	if cover := strings.Join(root.Cover(), ","); cover != "e1" {
+		t.Errorf("root cover = %v, expected e1", cover)
+	}
+	if cover := strings.Join(tree[1].Cover(), ","); cover != "2" {
+		t.Errorf("cover of 2 = %v, expected 2", cover)
+	}
+	if len(root.Children) != 2 || tree[1].Parent != root || tree[2].Parent != root {
+		t.Errorf("root has wrong children")
+	}
+}
+
+func TestParsePACETd(t *testing.T) {
+	tree := writerTestTree()
+	htPath := filepath.Join(t.TempDir(), "tree.td")
+	tree.WriteToFile(htPath)
+
+	root, parsed := ParseHypertree(htPath)
+	if root == nil || root.ID != 1 || len(parsed) != len(tree) {
+		t.Fatalf("root = %v, len(parsed) = %v", root, len(parsed))
+	}
+	for i, n := range parsed {
+		if strings.Join(n.Bag(), ",") != strings.Join(tree[i].Bag(), ",") {
+			t.Errorf("bag of %v = %v, expected %v", n.ID, n.Bag(), tree[i].Bag())
+		}
+	}
+}
+
+func TestParseBalancedGoFile(t *testing.T) {
+	htPath := writeTestFile(t, "tree.txt", `Bag: {a, b, c}
+Cover: {e1, e2}
+Children: [
+Bag: {c, d}
+Cover: {e3}
+Children: [
+]
+Bag: {a, e}
+Cover: {e4}
+Children: [
+]
+]
+`)
+	root, tree := ParseHypertree(htPath)
+	if root == nil || len(tree) != 3 || len(root.Children) != 2 {
+		t.Fatalf("root = %v, len(tree) = %v", root, len(tree))
+	}
+	if cover := strings.Join(root.Cover(), ","); cover != "e1,e2" {
+		t.Errorf("root cover = %v, expected e1,e2", cover)
+	}
+}
+
+func TestParsePACETdCovers(t *testing.T) {
+	htPath := writeTestFile(t, "tree.td", `s td 2 3 4
+b 1 1 2 3
+b 2 2 3 4
+1 2
+`)
+	hg := make(Hypergraph)
+	hg.AddEdge("ab", []string{"1", "2"})
+	hg.AddEdge("bc", []string{"2", "3"})
+	hg.AddEdge("cd", []string{"3", "4"})
+
+	_, nodes := ParseHypertree(htPath)
+	tree := Hypertree(nodes)
+	tree.Complete(hg)
+	for _, n := range tree {
+		if len(n.Cover()) == 0 {
+			t.Errorf("node %v has no cover", n.ID)
+		}
+	}
+	if err := tree.Validate(hg); err != nil {
+		t.Error(err)
+	}
+
+	tree[1].SetCover([]string{"cd"})
+	if err := tree.Validate(hg); err == nil {
+		t.Error("bag of node 2 is not covered, but the tree is valid")
+	}
+}
+
+func TestParsePACEBadWeight(t *testing.T) {
+	htPath := writeTestFile(t, "tree.htd", `s htd 1 1 2 1
+b 1 1 2
+w 1 1 x
+`)
+	defer func() {
+		if recover() == nil {
+			t.Error("bad weight parsed")
+		}
+	}()
+	ParseHypertree(htPath)
+}
+
+func TestParseDetKGML(t *testing.T) {
+	htPath := writeTestFile(t, "tree.gml", `graph [
+  directed 0
+  node [
+    id 0
+    label "{e1, e2}    {a, b, c}"
+    vgj [
+      labelPosition "in"
+      shape "Rectangle"
+    ]
+  ]
+  node [
+    id 1
+    label "{e3}    {c, d}"
+    vgj [
+      labelPosition "in"
+      shape "Rectangle"
+    ]
+  ]
+  edge [
+    source 0
+    target 1
+  ]
+]
+`)
+	root, tree := ParseHypertree(htPath)
+	if root == nil || root.ID != 0 || len(tree) != 2 || len(root.Children) != 1 {
+		t.Fatalf("root = %v, len(tree) = %v", root, len(tree))
+	}
+	if cover, bag := strings.Join(root.Cover(), ","), strings.Join(root.Bag(), ","); cover != "e1,e2" || bag != "a,b,c" {
+		t.Errorf("root cover = %v and bag = %v, expected e1,e2 and a,b,c", cover, bag)
+	}
+}
+
+func TestParsePACEEmpty(t *testing.T) {
+	htPath := writeTestFile(t, "tree.td", "s td 0 0 3\n")
+	defer func() {
+		if recover() == nil {
+			t.Error("empty decomposition parsed")
+		}
+	}()
+	ParsePACE(htPath)
+}
@@ -0,0 +1,112 @@
+package decomp
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/dmlongo/callidus/csp"
+	"github.com/dmlongo/callidus/db"
+	"github.com/dmlongo/callidus/files"
+)
+
+// SolveAndReduce solves the CSPs associated to a hypertree in parallel and
+// pipelines them with the upward semijoins of Yannakakis' algorithm: a node
+// is semijoined into its parent as soon as both of their tables are
+// complete and the node has been reduced by all of its children.
+// It stops all solvers and returns false as soon as a table is empty, or a
+// solver exceeds the limits in opts.
+func SolveAndReduce(ctx context.Context, root *Node, domains map[string]string, constraints map[string]csp.Constraint, baseDir string, opts SubOptions) (bool, error) {
+	subCspFolder := files.MakeDir(baseDir + "subs/")
+	nodes := Bfs(root)
+
+	// leaves first, so that reductions can start early
+	jobs := make(chan *Node, len(nodes))
+	for i := len(nodes) - 1; i >= 0; i-- {
+		jobs <- nodes[i]
+	}
+	close(jobs)
+
+	type event struct {
+		node   *Node
+		sat    bool
+		err    error
+		solved bool // the table of node is complete, otherwise node was semijoined into its parent
+	}
+	events := make(chan event)
+	ctx, cancel := withLimit(ctx, opts.Timeout)
+	defer cancel()
+
+	numWorkers := runtime.NumCPU()
+	if len(nodes) < numWorkers {
+		numWorkers = len(nodes)
+	}
+	for i := 0; i < numWorkers; i++ {
+		go func() { // launch a worker
+			for n := range jobs {
+				if ctx.Err() != nil {
+					return
+				}
+				sat, err := solveNode(ctx, n, constraints, domains, subCspFolder, opts)
+				select {
+				case events <- event{node: n, sat: sat, err: err, solved: true}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	reduceUp := func(n *Node) {
+		go func() {
+			parent := n.Parent
+			parent.Lock.Lock()
+			db.Semijoin(parent.Table, n.Table)
+			sat := !parent.Table.Empty()
+			parent.Lock.Unlock()
+			select {
+			case events <- event{node: n, sat: sat}:
+			case <-ctx.Done():
+			}
+		}()
+	}
+
+	solved := make(map[*Node]bool)
+	pending := make(map[*Node]int)
+	for _, n := range nodes {
+		pending[n] = len(n.Children)
+	}
+	ready := func(n *Node) bool {
+		return solved[n] && pending[n] == 0
+	}
+	for {
+		var ev event
+		select {
+		case ev = <-events:
+		case <-ctx.Done(): // events are dropped once ctx is done
+			return false, limitError(ctx, -1)
+		}
+		if ev.err != nil || !ev.sat {
+			return false, ev.err
+		}
+		n := ev.node
+		if ev.solved {
+			solved[n] = true
+			for _, c := range n.Children {
+				if ready(c) {
+					reduceUp(c)
+				}
+			}
+		} else {
+			n = n.Parent
+			pending[n]--
+		}
+		if ready(n) {
+			if n.Parent == nil {
+				return true, nil
+			}
+			if solved[n.Parent] {
+				reduceUp(n)
+			}
+		}
+	}
+}
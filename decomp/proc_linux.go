@@ -0,0 +1,87 @@
+//go:build linux
+// +build linux
+
+package decomp
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// setProcessGroup makes a command run in its own process group
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcessGroup kills a command started with setProcessGroup and all of its children
+func killProcessGroup(cmd *exec.Cmd) error {
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
+
+// groupMemory is the resident set size of all processes in a process group, in bytes.
+// Solvers are often started by scripts, so their memory is in the children.
+func groupMemory(pgid int) (uint64, error) {
+	dir, err := os.Open("/proc")
+	if err != nil {
+		return 0, err
+	}
+	names, err := dir.Readdirnames(-1)
+	dir.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	var total uint64
+	found := false
+	for _, name := range names {
+		pid, err := strconv.Atoi(name)
+		if err != nil {
+			continue
+		}
+		group, rss, err := processStat(pid)
+		if err != nil { // the process has exited
+			continue
+		}
+		if group == pgid {
+			total += rss
+			found = true
+		}
+	}
+	if !found {
+		return 0, errors.New("process group " + strconv.Itoa(pgid) + " not found")
+	}
+	return total, nil
+}
+
+// processStat reads the process group and the resident set size (in bytes) of a process
+func processStat(pid int) (int, uint64, error) {
+	data, err := ioutil.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return 0, 0, err
+	}
+	// the command name is between parentheses and can contain spaces,
+	// then come the state (3rd field), ..., pgrp (5th), ..., rss (24th, in pages)
+	end := bytes.LastIndexByte(data, ')')
+	if end < 0 {
+		return 0, 0, errors.New("cannot parse stat of process " + strconv.Itoa(pid))
+	}
+	fields := strings.Fields(string(data[end+1:]))
+	if len(fields) < 22 {
+		return 0, 0, errors.New("cannot parse stat of process " + strconv.Itoa(pid))
+	}
+	pgrp, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return 0, 0, err
+	}
+	pages, err := strconv.ParseUint(fields[21], 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return pgrp, pages * uint64(os.Getpagesize()), nil
+}
@@ -0,0 +1,33 @@
+//go:build linux
+// +build linux
+
+package decomp
+
+import (
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestGroupMemory(t *testing.T) {
+	// like a solver started by a script, the leader waits for its children
+	cmd := exec.Command("sh", "-c", "sleep 30 & sleep 30 & wait")
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() {
+		killProcessGroup(cmd)
+		cmd.Wait()
+	}()
+	time.Sleep(200 * time.Millisecond)
+
+	_, leader, err := processStat(cmd.Process.Pid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mem, err := groupMemory(cmd.Process.Pid)
+	if err != nil || mem <= leader {
+		t.Errorf("groupMemory = %v, %v, expected more than the %v bytes of the leader", mem, err, leader)
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux
+// +build !linux
+
+package decomp
+
+import (
+	"errors"
+	"os/exec"
+)
+
+func setProcessGroup(cmd *exec.Cmd) {}
+
+func killProcessGroup(cmd *exec.Cmd) error {
+	return cmd.Process.Kill()
+}
+
+func groupMemory(pgid int) (uint64, error) {
+	return 0, errors.New("memory of processes not available on this platform")
+}
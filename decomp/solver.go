@@ -0,0 +1,174 @@
+package decomp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Solver is an adapter for an external solver of sub-CSPs
+type Solver interface {
+	// Command that prints all solutions of cspFile, or of the instance on
+	// its standard input if cspFile is empty
+	Command(cspFile string) *exec.Cmd
+	// Stdin tells whether the solver can read instances from its standard input
+	Stdin() bool
+	// ReadSolutions parses the output of the command, and calls found on every solution
+	ReadSolutions(r io.Reader, found func(vars []string, vals []int)) error
+	// Succeeded tells whether the command ended well when it exits with exitCode
+	Succeeded(exitCode int) bool
+}
+
+// ErrTruncated is returned when the output of a solver ends in the middle of a solution
+var ErrTruncated = errors.New("truncated solver output")
+
+// NewSolver returns the adapter of the given kind of solver: nacre, or xcsp for
+// any solver that prints solutions as XCSP3 v lines. The command line runs the
+// solver on the file in place of {}, or at the end if there is no {}.
+// It is optional for nacre. If stdinArg is not empty, it is put in place of
+// the file to make the solver read instances from its standard input.
+func NewSolver(kind string, cmdLine string, stdinArg string) (Solver, error) {
+	args := strings.Fields(cmdLine)
+	switch kind {
+	case "nacre":
+		if len(args) == 0 {
+			args = []string{nacre, "{}", "-complete", "-sols", "-verb=3"}
+		}
+		if stdinArg == "" && runtime.GOOS == "linux" {
+			stdinArg = "/dev/stdin"
+		}
+		return &nacreSolver{xcspSolver{args: args, stdinArg: stdinArg}}, nil
+	case "xcsp":
+		if len(args) == 0 {
+			return nil, errors.New("xcsp solver without a command line")
+		}
+		return &xcspSolver{args: args, stdinArg: stdinArg}, nil
+	default:
+		return nil, fmt.Errorf("%v solver not implemented", kind)
+	}
+}
+
+// xcspSolver runs a command that prints its solutions as in the XCSP3 competitions,
+// i.e., an instantiation over one or more lines starting with v
+type xcspSolver struct {
+	args     []string
+	stdinArg string
+}
+
+func (s *xcspSolver) Command(cspFile string) *exec.Cmd {
+	if cspFile == "" {
+		cspFile = s.stdinArg
+	}
+	args := make([]string, 0, len(s.args)+1)
+	placed := false
+	for _, a := range s.args {
+		if strings.Contains(a, "{}") {
+			a = strings.ReplaceAll(a, "{}", cspFile)
+			placed = true
+		}
+		args = append(args, a)
+	}
+	if !placed {
+		args = append(args, cspFile)
+	}
+	return exec.Command(args[0], args[1:]...)
+}
+
+func (s *xcspSolver) Stdin() bool {
+	return s.stdinArg != ""
+}
+
+func (s *xcspSolver) ReadSolutions(r io.Reader, found func(vars []string, vals []int)) error {
+	reader := bufio.NewReader(r)
+	var inst strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			if line != "" || inst.Len() > 0 {
+				return ErrTruncated
+			}
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !strings.HasPrefix(line, "v") {
+			continue
+		}
+		inst.WriteString(strings.TrimPrefix(line, "v"))
+		if !strings.Contains(line, "</instantiation>") {
+			continue
+		}
+		vars, vals, err := parseInstantiation(inst.String())
+		if err != nil {
+			return err
+		}
+		found(vars, vals)
+		inst.Reset()
+	}
+}
+
+func (s *xcspSolver) Succeeded(exitCode int) bool {
+	return exitCode == 0
+}
+
+// nacreSolver prints one solution per v line, and exits with 40 after
+// finding all solutions
+type nacreSolver struct {
+	xcspSolver
+}
+
+func (s *nacreSolver) Succeeded(exitCode int) bool {
+	return exitCode == 0 || exitCode == 40
+}
+
+// parseInstantiation parses the variables and values of an XCSP3 instantiation.
+// Values may be compressed as vxk (k times v).
+func parseInstantiation(inst string) ([]string, []int, error) {
+	list, ok := element(inst, "list")
+	if !ok {
+		return nil, nil, fmt.Errorf("bad list= %s", inst)
+	}
+	values, ok := element(inst, "values")
+	if !ok {
+		return nil, nil, fmt.Errorf("bad values= %s", inst)
+	}
+
+	vars := strings.Fields(list)
+	vals := make([]int, 0, len(vars))
+	for _, tk := range strings.Fields(values) {
+		times := 1
+		if x := strings.IndexByte(tk, 'x'); x >= 0 {
+			k, err := strconv.Atoi(tk[x+1:])
+			if err != nil {
+				return nil, nil, fmt.Errorf("bad value %s: %v", tk, err)
+			}
+			tk, times = tk[:x], k
+		}
+		v, err := strconv.Atoi(tk)
+		if err != nil {
+			return nil, nil, fmt.Errorf("bad value %s: %v", tk, err)
+		}
+		for i := 0; i < times; i++ {
+			vals = append(vals, v)
+		}
+	}
+	if len(vals) != len(vars) {
+		return nil, nil, fmt.Errorf("%v values for %v variables in %s", len(vals), len(vars), inst)
+	}
+	return vars, vals, nil
+}
+
+// element returns the content of the first <tag>...</tag> in s
+func element(s string, tag string) (string, bool) {
+	start := strings.Index(s, "<"+tag+">")
+	end := strings.Index(s, "</"+tag+">")
+	if start < 0 || end < start {
+		return "", false
+	}
+	return s[start+len(tag)+2 : end], true
+}
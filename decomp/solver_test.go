@@ -0,0 +1,63 @@
+package decomp
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadSolutions(t *testing.T) {
+	out := `c some comment
+v <instantiation> <list> x y </list> <values>0 1 </values> </instantiation>
+(0) Assigned x to 0
+v <instantiation type="solution">
+v   <list> x y z </list>
+v   <values> 2x2 5 </values>
+v </instantiation>
+s SATISFIABLE
+`
+	s, err := NewSolver("xcsp", "solver -all", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sols [][]int
+	var lists [][]string
+	err = s.ReadSolutions(strings.NewReader(out), func(vars []string, vals []int) {
+		lists = append(lists, vars)
+		sols = append(sols, vals)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expLists := [][]string{{"x", "y"}, {"x", "y", "z"}}
+	expSols := [][]int{{0, 1}, {2, 2, 5}}
+	if !reflect.DeepEqual(lists, expLists) || !reflect.DeepEqual(sols, expSols) {
+		t.Errorf("read %v %v, expected %v %v", lists, sols, expLists, expSols)
+	}
+
+	truncated := "v <instantiation>\nv <list> x </list>\n"
+	if err := s.ReadSolutions(strings.NewReader(truncated), func([]string, []int) {}); err != ErrTruncated {
+		t.Errorf("truncated output returned %v", err)
+	}
+	bad := "v <instantiation> <list> x y </list> <values> 1 </values> </instantiation>\n"
+	if err := s.ReadSolutions(strings.NewReader(bad), func([]string, []int) {}); err == nil {
+		t.Error("missing value not detected")
+	}
+}
+
+func TestSolverCommand(t *testing.T) {
+	s, _ := NewSolver("xcsp", "java -jar ace.jar {} -s=all", "")
+	if args := s.Command("sub1.xml").Args; !reflect.DeepEqual(args, []string{"java", "-jar", "ace.jar", "sub1.xml", "-s=all"}) {
+		t.Errorf("command is %v", args)
+	}
+	s, _ = NewSolver("xcsp", "choco -a", "")
+	if args := s.Command("sub1.xml").Args; !reflect.DeepEqual(args, []string{"choco", "-a", "sub1.xml"}) {
+		t.Errorf("command is %v", args)
+	}
+	if _, err := NewSolver("xcsp", "", ""); err == nil {
+		t.Error("xcsp solver without a command line")
+	}
+	if s, _ = NewSolver("nacre", "", ""); !s.Succeeded(40) || s.Command("f").Args[1] != "f" {
+		t.Error("bad default nacre")
+	}
+}
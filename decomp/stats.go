@@ -0,0 +1,101 @@
+package decomp
+
+import (
+	"math"
+
+	"github.com/dmlongo/callidus/csp"
+)
+
+// HypergraphStats collects structural properties of a hypergraph
+type HypergraphStats struct {
+	Vertices    int         `json:"vertices"`
+	Edges       int         `json:"edges"`
+	MaxEdgeSize int         `json:"maxEdgeSize"`
+	AvgEdgeSize float64     `json:"avgEdgeSize"`
+	MaxDegree   int         `json:"maxDegree"`
+	AvgDegree   float64     `json:"avgDegree"`
+	Degrees     map[int]int `json:"degrees"` // number of vertices for each degree
+}
+
+// Stats of a hypergraph
+func (hg Hypergraph) Stats() HypergraphStats {
+	s := HypergraphStats{Edges: len(hg), Degrees: make(map[int]int)}
+	degree := make(map[string]int)
+	sumSizes := 0
+	for _, e := range hg {
+		sumSizes += len(e.vertices)
+		if len(e.vertices) > s.MaxEdgeSize {
+			s.MaxEdgeSize = len(e.vertices)
+		}
+		for _, v := range e.vertices {
+			degree[v]++
+		}
+	}
+	s.Vertices = len(degree)
+	sumDegrees := 0
+	for _, d := range degree {
+		s.Degrees[d]++
+		sumDegrees += d
+		if d > s.MaxDegree {
+			s.MaxDegree = d
+		}
+	}
+	if s.Edges > 0 {
+		s.AvgEdgeSize = float64(sumSizes) / float64(s.Edges)
+	}
+	if s.Vertices > 0 {
+		s.AvgDegree = float64(sumDegrees) / float64(s.Vertices)
+	}
+	return s
+}
+
+// HypertreeStats collects structural properties of a hypertree
+type HypertreeStats struct {
+	Nodes        int     `json:"nodes"`
+	Width        int     `json:"width"`
+	Depth        int     `json:"depth"`
+	MaxBagSize   int     `json:"maxBagSize"`
+	AvgBagSize   float64 `json:"avgBagSize"`
+	MaxTableSize float64 `json:"maxTableSize"` // estimated with the domains of the bag
+	SumTableSize float64 `json:"sumTableSize"` // estimated with the domains of the bag
+}
+
+// Stats of a hypertree. Table sizes are estimated as the product of the
+// domain sizes of the variables in each bag, up to math.MaxFloat64.
+func (tree Hypertree) Stats(domains map[string]string) HypertreeStats {
+	s := HypertreeStats{Nodes: len(tree)}
+	if root := tree.Root(); root != nil {
+		s.Depth = Height(root)
+	}
+	sumBags := 0
+	for _, n := range tree {
+		if len(n.cover) > s.Width {
+			s.Width = len(n.cover)
+		}
+		if len(n.bag) > s.MaxBagSize {
+			s.MaxBagSize = len(n.bag)
+		}
+		sumBags += len(n.bag)
+
+		size := 1.0
+		for _, v := range n.bag {
+			size = clampSize(size * float64(csp.DomainSize(domains[v])))
+		}
+		s.SumTableSize = clampSize(s.SumTableSize + size)
+		if size > s.MaxTableSize {
+			s.MaxTableSize = size
+		}
+	}
+	if s.Nodes > 0 {
+		s.AvgBagSize = float64(sumBags) / float64(s.Nodes)
+	}
+	return s
+}
+
+// clampSize keeps estimated sizes finite, so that they can be encoded as JSON
+func clampSize(size float64) float64 {
+	if size > math.MaxFloat64 {
+		return math.MaxFloat64
+	}
+	return size
+}
@@ -0,0 +1,41 @@
+package decomp
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/dmlongo/callidus/db"
+)
+
+const treeFile = "hypertree.gml"
+
+// SaveTables writes in dir the hypertree and the table of each of its nodes
+// (subN.tab, in binary format), so that they can be reloaded with LoadTables
+func SaveTables(tree Hypertree, dir string) {
+	if err := os.RemoveAll(dir); err != nil {
+		panic(err)
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		panic(err)
+	}
+	tree.WriteToFile(filepath.Join(dir, treeFile))
+	for _, node := range tree {
+		db.RelToBinFile(tableFile(dir, node), node.Table)
+	}
+}
+
+// LoadTables reads a hypertree and the tables of its nodes saved by SaveTables
+func LoadTables(dir string) (*Node, Hypertree) {
+	root, tree := ParseGML(filepath.Join(dir, treeFile))
+	for _, node := range tree {
+		node.Table = db.RelFromBinFile(tableFile(dir, node))
+		node.Table.SetDistinct(DistinctTables)
+		node.SetBag(node.Table.Attributes())
+	}
+	return root, tree
+}
+
+func tableFile(dir string, n *Node) string {
+	return filepath.Join(dir, "sub"+strconv.Itoa(n.ID)+".tab")
+}
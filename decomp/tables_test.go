@@ -0,0 +1,42 @@
+package decomp
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/dmlongo/callidus/db"
+)
+
+func TestSaveLoadTables(t *testing.T) {
+	n0 := NewNode(0, []string{"c", "a", "b"}, []string{"e1", "e2"})
+	n1 := NewNode(1, []string{"c", "d"}, []string{"e3"})
+	n0.AddChild(n1)
+	n0.Table.AddTuple([]int{1, 2, 3})
+	n0.Table.AddTuple([]int{4, 5, 6})
+	n1.Table.AddTuple([]int{1, 7})
+	tree := Hypertree{n0, n1}
+
+	dir := filepath.Join(t.TempDir(), "tables")
+	SaveTables(tree, dir)
+	root, loaded := LoadTables(dir)
+	if root == nil || root.ID != 0 || len(loaded) != 2 {
+		t.Fatalf("root = %v, %v nodes, expected 0 and 2", root, len(loaded))
+	}
+	for i, n := range loaded {
+		exp := tree[i]
+		if strings.Join(n.Bag(), ",") != strings.Join(exp.Bag(), ",") {
+			t.Errorf("node %v has bag %v, expected %v", n.ID, n.Bag(), exp.Bag())
+		}
+		if db.RelToString(n.Table) != db.RelToString(exp.Table) {
+			t.Errorf("node %v has table\n%s, expected\n%s", n.ID, db.RelToString(n.Table), db.RelToString(exp.Table))
+		}
+	}
+
+	defer func() { DistinctTables = false }()
+	DistinctTables = true
+	_, loaded = LoadTables(dir)
+	if _, added := loaded[0].Table.AddTuple([]int{1, 2, 3}); added {
+		t.Error("duplicate added to a loaded table with DistinctTables")
+	}
+}
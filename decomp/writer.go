@@ -0,0 +1,159 @@
+package decomp
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WriteToFile a hypertree in the format given by the extension of htPath (.gml, .dot or .td)
+func (tree Hypertree) WriteToFile(htPath string) {
+	file, err := os.Create(htPath)
+	if err != nil {
+		panic(err)
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			panic(err)
+		}
+	}()
+
+	switch filepath.Ext(htPath) {
+	case ".gml":
+		tree.WriteGML(file)
+	case ".dot", ".gv":
+		tree.WriteDOT(file)
+	case ".td":
+		tree.WriteTD(file)
+	default:
+		panic("Unknown hypertree format: " + htPath)
+	}
+}
+
+// WriteGML writes a hypertree in GML format (readable by ParseGML)
+func (tree Hypertree) WriteGML(out io.Writer) {
+	nodes := tree.ordered()
+	w := bufio.NewWriter(out)
+	writeLine(w, "graph [")
+	writeLine(w, "\tdirected 1")
+	for _, n := range nodes {
+		writeLine(w, "\tnode [")
+		writeLine(w, "\t\tid "+strconv.Itoa(n.ID))
+		writeLine(w, "\t\tlabel \"{"+strings.Join(n.cover, ", ")+"} {"+strings.Join(n.bag, ", ")+"}\"")
+		writeLine(w, "\t]")
+	}
+	for _, n := range nodes {
+		for _, c := range n.Children {
+			writeLine(w, "\tedge [")
+			writeLine(w, "\t\tsource "+strconv.Itoa(n.ID))
+			writeLine(w, "\t\ttarget "+strconv.Itoa(c.ID))
+			writeLine(w, "\t]")
+		}
+	}
+	writeLine(w, "]")
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
+}
+
+// WriteDOT writes a hypertree in the Graphviz DOT format
+func (tree Hypertree) WriteDOT(out io.Writer) {
+	nodes := tree.ordered()
+	w := bufio.NewWriter(out)
+	writeLine(w, "digraph hypertree {")
+	writeLine(w, "\tnode [shape=box];")
+	for _, n := range nodes {
+		var label strings.Builder
+		label.WriteString("ID: " + strconv.Itoa(n.ID))
+		label.WriteString("\\nBag: {" + strings.Join(n.bag, ", ") + "}")
+		label.WriteString("\\nCover: {" + strings.Join(n.cover, ", ") + "}")
+		if n.Table != nil && !n.Table.Empty() {
+			label.WriteString("\\nTable: " + strconv.Itoa(n.Table.Size()))
+		}
+		writeLine(w, "\tn"+strconv.Itoa(n.ID)+" [label=\""+strings.ReplaceAll(label.String(), "\"", "\\\"")+"\"];")
+	}
+	for _, n := range nodes {
+		for _, c := range n.Children {
+			writeLine(w, "\tn"+strconv.Itoa(n.ID)+" -> n"+strconv.Itoa(c.ID)+";")
+		}
+	}
+	writeLine(w, "}")
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
+}
+
+// WriteTD writes a hypertree in the PACE .td format.
+// Vertices are numbered following their lexicographic order, and comment
+// lines "c v <number> <name>" record their original names.
+func (tree Hypertree) WriteTD(out io.Writer) {
+	nodes := tree.ordered()
+	vertices, vertexIdx := tree.vertexNumbering()
+	bagIdx := make(map[*Node]int)
+	maxBag := 0
+	for i, n := range nodes {
+		bagIdx[n] = i + 1
+		if len(n.bag) > maxBag {
+			maxBag = len(n.bag)
+		}
+	}
+
+	w := bufio.NewWriter(out)
+	writeLine(w, fmt.Sprintf("s td %v %v %v", len(nodes), maxBag, len(vertices)))
+	for i, v := range vertices {
+		writeLine(w, fmt.Sprintf("c v %v %s", i+1, v))
+	}
+	for _, n := range nodes {
+		var sb strings.Builder
+		sb.WriteString("b " + strconv.Itoa(bagIdx[n]))
+		for _, v := range n.bag {
+			sb.WriteString(" " + strconv.Itoa(vertexIdx[v]))
+		}
+		writeLine(w, sb.String())
+	}
+	for _, n := range nodes {
+		for _, c := range n.Children {
+			writeLine(w, strconv.Itoa(bagIdx[n])+" "+strconv.Itoa(bagIdx[c]))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
+}
+
+// ordered nodes of a hypertree, starting from the root
+func (tree Hypertree) ordered() []*Node {
+	if root := tree.Root(); root != nil {
+		return Bfs(root)
+	}
+	return tree
+}
+
+func (tree Hypertree) vertexNumbering() ([]string, map[string]int) {
+	idx := make(map[string]int)
+	var vertices []string
+	for _, n := range tree {
+		for _, v := range n.bag {
+			if _, ok := idx[v]; !ok {
+				idx[v] = 0
+				vertices = append(vertices, v)
+			}
+		}
+	}
+	sort.Strings(vertices)
+	for i, v := range vertices {
+		idx[v] = i + 1
+	}
+	return vertices, idx
+}
+
+func writeLine(w *bufio.Writer, line string) {
+	if _, err := w.WriteString(line + "\n"); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,71 @@
+package decomp
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writerTestTree() Hypertree {
+	n0 := NewNode(0, []string{"a", "b", "c"}, []string{"e1", "e2"})
+	n1 := NewNode(1, []string{"c", "d"}, []string{"e3"})
+	n2 := NewNode(2, []string{"a", "e"}, []string{"e4"})
+	n0.AddChild(n1)
+	n0.AddChild(n2)
+	return Hypertree{n0, n1, n2}
+}
+
+func TestWriteGML(t *testing.T) {
+	tree := writerTestTree()
+	htPath := filepath.Join(t.TempDir(), "tree.gml")
+	tree.WriteToFile(htPath)
+
+	root, parsed := ParseGML(htPath)
+	if root == nil || root.ID != 0 {
+		t.Fatalf("root = %v, expected 0", root)
+	}
+	if len(parsed) != len(tree) {
+		t.Fatalf("len(parsed) = %v, expected %v", len(parsed), len(tree))
+	}
+	for i, n := range parsed {
+		exp := tree[i]
+		if n.ID != exp.ID || strings.Join(n.Bag(), ",") != strings.Join(exp.Bag(), ",") || strings.Join(n.Cover(), ",") != strings.Join(exp.Cover(), ",") {
+			t.Errorf("parsed node %v {%v} {%v}, expected %v {%v} {%v}", n.ID, n.Cover(), n.Bag(), exp.ID, exp.Cover(), exp.Bag())
+		}
+		if len(n.Children) != len(exp.Children) {
+			t.Errorf("node %v has %v children, expected %v", n.ID, len(n.Children), len(exp.Children))
+		}
+	}
+}
+
+func TestWriteTD(t *testing.T) {
+	var buf bytes.Buffer
+	writerTestTree().WriteTD(&buf)
+	expected := []string{
+		"s td 3 3 5",
+		"c v 1 a", "c v 2 b", "c v 3 c", "c v 4 d", "c v 5 e",
+		"b 1 1 2 3", "b 2 3 4", "b 3 1 5",
+		"1 2", "1 3",
+	}
+	result := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(result) != len(expected) {
+		t.Fatalf("result = %q, expected %q", result, expected)
+	}
+	for i := range result {
+		if result[i] != expected[i] {
+			t.Errorf("result[%v] = %q, expected %q", i, result[i], expected[i])
+		}
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	var buf bytes.Buffer
+	writerTestTree().WriteDOT(&buf)
+	res := buf.String()
+	for _, s := range []string{"digraph hypertree {", "n0 -> n1;", "n0 -> n2;", "Bag: {c, d}", "Cover: {e1, e2}"} {
+		if !strings.Contains(res, s) {
+			t.Errorf("%q not found in\n%s", s, res)
+		}
+	}
+}
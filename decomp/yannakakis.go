@@ -1,6 +1,7 @@
 package decomp
 
 import (
+	"context"
 	"fmt"
 	"runtime"
 	"sync"
@@ -13,73 +14,96 @@ import (
 type Yannakakis interface {
 	//csp.Solver
 
-	// Solve of the problem represented by the given tree
-	Solve() (csp.Solution, bool)
+	// Solve of the problem represented by the given tree.
+	// It stops early with ctx.Err() when ctx is done.
+	Solve(ctx context.Context) (csp.Solution, bool, error)
 
-	// AllSolutions of the problem represent by the given tree
-	AllSolutions() []csp.Solution
+	// AllSolutions of the problem represent by the given tree.
+	// It stops early with ctx.Err() when ctx is done.
+	AllSolutions(ctx context.Context) ([]csp.Solution, error)
 
 	// reduce a tree with upwards semijoins
-	reduce(root *Node) bool
+	reduce(ctx context.Context, root *Node) bool
 	// fullyReduce a tree with downwards semijoins (after reduce)
-	fullyReduce(root *Node)
+	fullyReduce(ctx context.Context, root *Node)
 	// joinUpwards a tree to compute all solutions (after fullyReduce)
-	joinUpwards(root *Node) ([]string, db.Relation)
+	joinUpwards(ctx context.Context, root *Node) ([]string, db.Relation)
 }
 
 func NewYannakakis(tree *Node, mode string) (Yannakakis, error) {
 	switch mode {
 	case "seq":
-		return &seqY{tree: tree}, nil
+		return &seqY{yResults{tree: tree}}, nil
 	case "par":
-		return &parY{tree: tree}, nil
-	//case "ymca":
-	//	return &ymca{tree: tree}, nil
+		return &parY{yResults{tree: tree}}, nil
+	case "ymca":
+		return &ymca{yResults{tree: tree}}, nil
 	default:
 		return nil, fmt.Errorf("%v yannakakis not implemented", mode)
 	}
 }
 
-type seqY struct {
+// yResults of Yannakakis' algorithm on a tree, shared by its implementations
+type yResults struct {
 	tree *Node
 	sol  csp.Solution
 	all  []csp.Solution
 }
 
-func (y *seqY) Solve() (csp.Solution, bool) {
-	if y.sol == nil {
-		if y.reduce(y.tree) {
+// solve the tree with the phases of y, once
+func (res *yResults) solve(ctx context.Context, y Yannakakis) (csp.Solution, bool, error) {
+	if res.sol == nil {
+		sat := y.reduce(ctx, res.tree)
+		if err := ctx.Err(); err != nil {
+			return nil, false, err
+		}
+		if sat {
 			// TODO backtrack
 			// measure time diff of back with und ohne fullyReduce
-			y.sol = csp.Solution{"": 0}
+			res.sol = csp.Solution{"": 0}
 		} else {
-			y.sol = csp.Solution{}
+			res.sol = csp.Solution{}
 		}
 	}
-	if len(y.sol) == 0 {
-		return y.sol, false
-	} else {
-		return y.sol, true
-	}
+	return res.sol, len(res.sol) > 0, nil
 }
 
-func (y *seqY) AllSolutions() []csp.Solution { // TODO
-	if y.all == nil {
-		y.fullyReduce(y.tree)
-		_, rel := y.joinUpwards(y.tree)
+// allSolutions of the tree computed with the phases of y, once
+func (res *yResults) allSolutions(ctx context.Context, y Yannakakis) ([]csp.Solution, error) { // TODO
+	if res.all == nil {
+		y.fullyReduce(ctx, res.tree)
+		_, rel := y.joinUpwards(ctx, res.tree)
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 
 		fmt.Print("(Conversion from Relation to Solution... ")
 		startConversion := time.Now()
-		y.all = db.RelToSolutions(rel)
+		res.all = db.RelToSolutions(rel)
 		fmt.Print("done in ", time.Since(startConversion), ") ")
 	}
-	return y.all
+	return res.all, nil
+}
+
+type seqY struct {
+	yResults
+}
+
+func (y *seqY) Solve(ctx context.Context) (csp.Solution, bool, error) {
+	return y.solve(ctx, y)
+}
+
+func (y *seqY) AllSolutions(ctx context.Context) ([]csp.Solution, error) {
+	return y.allSolutions(ctx, y)
 }
 
-func (y *seqY) reduce(root *Node) bool {
+func (y *seqY) reduce(ctx context.Context, root *Node) bool {
+	if root.Table.Empty() {
+		return false
+	}
 	// bottom-up
 	for _, child := range root.Children {
-		if !y.reduce(child) {
+		if !y.reduce(ctx, child) || ctx.Err() != nil {
 			return false
 		}
 		db.Semijoin(root.Table, child.Table)
@@ -90,17 +114,23 @@ func (y *seqY) reduce(root *Node) bool {
 	return true
 }
 
-func (y *seqY) fullyReduce(root *Node) {
+func (y *seqY) fullyReduce(ctx context.Context, root *Node) {
 	// top-down
 	for _, child := range root.Children {
+		if ctx.Err() != nil {
+			return
+		}
 		db.Semijoin(child.Table, root.Table)
-		y.fullyReduce(child)
+		y.fullyReduce(ctx, child)
 	}
 }
 
-func (y *seqY) joinUpwards(curr *Node) ([]string, db.Relation) {
+func (y *seqY) joinUpwards(ctx context.Context, curr *Node) ([]string, db.Relation) {
 	for _, child := range curr.Children {
-		childBag, childTuples := y.joinUpwards(child)
+		childBag, childTuples := y.joinUpwards(ctx, child)
+		if ctx.Err() != nil {
+			break
+		}
 		child.SetBag(childBag)
 		child.Table = childTuples
 
@@ -111,43 +141,41 @@ func (y *seqY) joinUpwards(curr *Node) ([]string, db.Relation) {
 	return curr.bag, curr.Table
 }
 
-type parY struct {
-	tree *Node
-	sol  csp.Solution
-	all  []csp.Solution
-}
+// ParThreshold is the number of tuples of two tables above which parY
+// semijoins and joins them with the parallel operators of db
+var ParThreshold = 100000
 
-func (y *parY) Solve() (csp.Solution, bool) {
-	if y.sol == nil {
-		if y.reduce(y.tree) {
-			// TODO backtrack
-			// measure time diff of back with und ohne fullyReduce
-			y.sol = csp.Solution{"": 0}
-		} else {
-			y.sol = csp.Solution{}
-		}
-	}
-	if len(y.sol) == 0 {
-		return y.sol, false
+func parSemijoin(l db.Relation, r db.Relation) {
+	if l.Size()+r.Size() > ParThreshold {
+		db.ParSemijoin(l, r)
 	} else {
-		return y.sol, true
+		db.Semijoin(l, r)
 	}
 }
 
-func (y *parY) AllSolutions() []csp.Solution { // TODO
-	if y.all == nil {
-		y.fullyReduce(y.tree)
-		_, rel := y.joinUpwards(y.tree)
-
-		fmt.Print("(Conversion from Relation to Solution... ")
-		startConversion := time.Now()
-		y.all = db.RelToSolutions(rel)
-		fmt.Print("done in ", time.Since(startConversion), ") ")
+func parJoin(l db.Relation, r db.Relation) db.Relation {
+	if l.Size()+r.Size() > ParThreshold {
+		return db.ParJoin(l, r)
 	}
-	return y.all
+	return db.Join(l, r)
+}
+
+type parY struct {
+	yResults
+}
+
+func (y *parY) Solve(ctx context.Context) (csp.Solution, bool, error) {
+	return y.solve(ctx, y)
+}
+
+func (y *parY) AllSolutions(ctx context.Context) ([]csp.Solution, error) {
+	return y.allSolutions(ctx, y)
 }
 
-func (y *parY) reduce(root *Node) bool {
+func (y *parY) reduce(ctx context.Context, root *Node) bool {
+	if len(root.Children) == 0 { // no semijoins to wait for
+		return !root.Table.Empty()
+	}
 	nodes := Bfs(root)
 	leaves := 0
 
@@ -192,7 +220,13 @@ func (y *parY) reduce(root *Node) bool {
 		close(numLeaves)
 
 		for len(deps) > 0 {
-			res := <-results
+			var res *result
+			select {
+			case res = <-results:
+			case <-ctx.Done():
+				sat <- false
+				return
+			}
 			if res.sat {
 				parentID := res.id
 				parent := id2node[parentID]
@@ -226,7 +260,7 @@ func (y *parY) reduce(root *Node) bool {
 		go func() { // launch a worker
 			for job := range jobs {
 				job.lock.Lock()
-				db.Semijoin(job.left, job.right)
+				parSemijoin(job.left, job.right)
 				sat := !job.left.Empty()
 				job.lock.Unlock()
 				select {
@@ -244,13 +278,16 @@ func (y *parY) reduce(root *Node) bool {
 	return <-sat
 }
 
-func (y *parY) fullyReduce(root *Node) {
+func (y *parY) fullyReduce(ctx context.Context, root *Node) {
 	var wg *sync.WaitGroup = &sync.WaitGroup{}
 	for _, child := range root.Children {
+		if ctx.Err() != nil {
+			break
+		}
 		wg.Add(1)
-		db.Semijoin(child.Table, root.Table)
+		parSemijoin(child.Table, root.Table)
 		go func(c *Node) {
-			y.fullyReduce(c)
+			y.fullyReduce(ctx, c)
 			wg.Done()
 		}(child)
 
@@ -258,18 +295,21 @@ func (y *parY) fullyReduce(root *Node) {
 	wg.Wait()
 }
 
-func (y *parY) joinUpwards(curr *Node) ([]string, db.Relation) {
+func (y *parY) joinUpwards(ctx context.Context, curr *Node) ([]string, db.Relation) {
 	var wg *sync.WaitGroup = &sync.WaitGroup{}
 	for _, child := range curr.Children {
 		wg.Add(1)
 		go func(child *Node) {
 			defer wg.Done()
-			childBag, childTuples := y.joinUpwards(child)
+			childBag, childTuples := y.joinUpwards(ctx, child)
+			if ctx.Err() != nil {
+				return
+			}
 			child.SetBag(childBag)
 			child.Table = childTuples
 
 			curr.Lock.Lock()
-			currRel := db.Join(curr.Table, child.Table)
+			currRel := parJoin(curr.Table, child.Table)
 			curr.SetBag(currRel.Attributes())
 			curr.Table = currRel
 			curr.Lock.Unlock()
@@ -1,23 +1,36 @@
 package decomp
 
 import (
+	"context"
 	"testing"
+	"time"
+
+	"github.com/dmlongo/callidus/csp"
 )
 
+// allSolutions of y, failing the test on errors
+func allSolutions(t *testing.T, y Yannakakis) []csp.Solution {
+	sols, err := y.AllSolutions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sols
+}
+
 func TestYannakSeq1(t *testing.T) {
 	input, partial, output, sols := test1Data()
 	y, _ := NewYannakakis(input, "seq")
-	if sat := y.reduce(input); !sat || !equals(input, partial) {
+	if sat := y.reduce(context.Background(), input); !sat || !equals(input, partial) {
 		if !sat {
 			t.Error("y(input) is unsat!")
 		}
 		t.Error("y(input) != partial")
 	}
-	y.fullyReduce(input)
+	y.fullyReduce(context.Background(), input)
 	if !equals(input, output) {
 		t.Error("y(partial) != output")
 	}
-	if !solEquals(y.AllSolutions(), sols) {
+	if !solEquals(allSolutions(t, y), sols) {
 		t.Error("y(output) != solutions")
 	}
 }
@@ -25,24 +38,24 @@ func TestYannakSeq1(t *testing.T) {
 func TestYannakSeq2(t *testing.T) {
 	input, partial, output, sols := test2Data()
 	y, _ := NewYannakakis(input, "seq")
-	if sat := y.reduce(input); !sat || !equals(input, partial) {
+	if sat := y.reduce(context.Background(), input); !sat || !equals(input, partial) {
 		if !sat {
 			t.Error("y(input) is unsat!")
 		}
 		t.Error("y(input) != partial")
 	}
-	y.fullyReduce(input)
+	y.fullyReduce(context.Background(), input)
 	if !equals(input, output) {
 		t.Error("y(partial) != output")
 	}
-	if !solEquals(y.AllSolutions(), sols) {
+	if !solEquals(allSolutions(t, y), sols) {
 		t.Error("y(output) != solutions")
 	}
 }
 
 func TestYannakSeq3(t *testing.T) {
 	input := test3Data()
-	if sat := (&seqY{}).reduce(input); sat {
+	if sat := (&seqY{}).reduce(context.Background(), input); sat {
 		t.Error("y(input) is sat!")
 	}
 }
@@ -50,17 +63,17 @@ func TestYannakSeq3(t *testing.T) {
 func TestYannakPar1(t *testing.T) {
 	input, partial, output, sols := test1Data()
 	y, _ := NewYannakakis(input, "par")
-	if sat := y.reduce(input); !sat || !equals(input, partial) {
+	if sat := y.reduce(context.Background(), input); !sat || !equals(input, partial) {
 		if !sat {
 			t.Error("y(input) is unsat!")
 		}
 		t.Error("y(input) != partial")
 	}
-	y.fullyReduce(input)
+	y.fullyReduce(context.Background(), input)
 	if !equals(input, output) {
 		t.Error("y(partial) != output")
 	}
-	if !solEquals(y.AllSolutions(), sols) {
+	if !solEquals(allSolutions(t, y), sols) {
 		t.Error("y(output) != solutions")
 	}
 }
@@ -68,24 +81,42 @@ func TestYannakPar1(t *testing.T) {
 func TestYannakPar2(t *testing.T) {
 	input, partial, output, sols := test2Data()
 	y, _ := NewYannakakis(input, "par")
-	if sat := y.reduce(input); !sat || !equals(input, partial) {
+	if sat := y.reduce(context.Background(), input); !sat || !equals(input, partial) {
 		if !sat {
 			t.Error("y(input) is unsat!")
 		}
 		t.Error("y(input) != partial")
 	}
-	y.fullyReduce(input)
+	y.fullyReduce(context.Background(), input)
 	if !equals(input, output) {
 		t.Error("y(partial) != output")
 	}
-	if !solEquals(y.AllSolutions(), sols) {
+	if !solEquals(allSolutions(t, y), sols) {
+		t.Error("y(output) != solutions")
+	}
+}
+
+func TestYannakParThreshold(t *testing.T) {
+	defer func(th int) { ParThreshold = th }(ParThreshold)
+	ParThreshold = 0
+
+	input, partial, output, sols := test2Data()
+	y, _ := NewYannakakis(input, "par")
+	if sat := y.reduce(context.Background(), input); !sat || !equals(input, partial) {
+		t.Fatal("y(input) != partial")
+	}
+	y.fullyReduce(context.Background(), input)
+	if !equals(input, output) {
+		t.Error("y(partial) != output")
+	}
+	if !solEquals(allSolutions(t, y), sols) {
 		t.Error("y(output) != solutions")
 	}
 }
 
 func TestYannakPar3(t *testing.T) {
 	input := test3Data()
-	if sat := (&parY{}).reduce(input); sat {
+	if sat := (&parY{}).reduce(context.Background(), input); sat {
 		t.Error("y(input) is sat!")
 	}
 }
@@ -93,17 +124,17 @@ func TestYannakPar3(t *testing.T) {
 func TestYannakYMCA1(t *testing.T) {
 	input, partial, output, sols := test1Data()
 	y, _ := NewYannakakis(input, "ymca")
-	if sat := y.reduce(input); !sat || !equals(input, partial) {
+	if sat := y.reduce(context.Background(), input); !sat || !equals(input, partial) {
 		if !sat {
 			t.Error("y(input) is unsat!")
 		}
 		t.Error("y(input) != partial")
 	}
-	y.fullyReduce(input)
+	y.fullyReduce(context.Background(), input)
 	if !equals(input, output) {
 		t.Error("y(partial) != output")
 	}
-	if !solEquals(y.AllSolutions(), sols) {
+	if !solEquals(allSolutions(t, y), sols) {
 		t.Error("y(output) != solutions")
 	}
 }
@@ -111,24 +142,100 @@ func TestYannakYMCA1(t *testing.T) {
 func TestYannakYMCA2(t *testing.T) {
 	input, partial, output, sols := test2Data()
 	y, _ := NewYannakakis(input, "ymca")
-	if sat := y.reduce(input); !sat || !equals(input, partial) {
+	if sat := y.reduce(context.Background(), input); !sat || !equals(input, partial) {
 		if !sat {
 			t.Error("y(input) is unsat!")
 		}
 		t.Error("y(input) != partial")
 	}
-	y.fullyReduce(input)
+	y.fullyReduce(context.Background(), input)
 	if !equals(input, output) {
 		t.Error("y(partial) != output")
 	}
-	if !solEquals(y.AllSolutions(), sols) {
+	if !solEquals(allSolutions(t, y), sols) {
 		t.Error("y(output) != solutions")
 	}
 }
 
-/*func TestYannakYMCA3(t *testing.T) {
+func TestYannakYMCA3(t *testing.T) {
 	input := test3Data()
-	if sat := (&ymca{}).reduce(input); sat {
+	if sat := (&ymca{}).reduce(context.Background(), input); sat {
 		t.Error("y(input) is sat!")
 	}
-}*/
+}
+
+func TestYannakYMCAvsSeq(t *testing.T) {
+	fixtures := []func() *Node{
+		func() *Node { input, _, _, _ := test1Data(); return input },
+		func() *Node { input, _, _, _ := test2Data(); return input },
+		test3Data,
+	}
+	for i, fixture := range fixtures {
+		seqIn, ymcaIn := fixture(), fixture()
+		seq, _ := NewYannakakis(seqIn, "seq")
+		y, _ := NewYannakakis(ymcaIn, "ymca")
+		seqSat, ymcaSat := seq.reduce(context.Background(), seqIn), y.reduce(context.Background(), ymcaIn)
+		if seqSat != ymcaSat {
+			t.Errorf("fixture %v: ymca sat=%v, seq sat=%v", i+1, ymcaSat, seqSat)
+			continue
+		}
+		if !seqSat {
+			continue
+		}
+		if !equals(seqIn, ymcaIn) {
+			t.Errorf("fixture %v: ymca and seq reduce differently", i+1)
+		}
+		seq.fullyReduce(context.Background(), seqIn)
+		y.fullyReduce(context.Background(), ymcaIn)
+		if !equals(seqIn, ymcaIn) {
+			t.Errorf("fixture %v: ymca and seq fully reduce differently", i+1)
+		}
+		if !solEquals(allSolutions(t, y), allSolutions(t, seq)) {
+			t.Errorf("fixture %v: ymca and seq have different solutions", i+1)
+		}
+	}
+}
+
+func TestYannakCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, mode := range []string{"seq", "par", "ymca"} {
+		input, _, _, _ := test1Data()
+		y, _ := NewYannakakis(input, mode)
+		if _, _, err := y.Solve(ctx); err != context.Canceled {
+			t.Errorf("%s: Solve returned %v, expected %v", mode, err, context.Canceled)
+		}
+		if _, err := y.AllSolutions(ctx); err != context.Canceled {
+			t.Errorf("%s: AllSolutions returned %v, expected %v", mode, err, context.Canceled)
+		}
+	}
+}
+
+func TestYannakNormalized(t *testing.T) {
+	hg := make(Hypergraph)
+	hg.AddEdge("e1", []string{"a", "b", "c"})
+	hg.AddEdge("e2", []string{"b", "c"})
+	for _, mode := range []string{"seq", "par", "ymca"} {
+		for _, sat := range []bool{true, false} {
+			root := NewNode(1, []string{"a", "b", "c"}, []string{"e1"})
+			root.AddChild(NewNode(2, []string{"b", "c"}, []string{"e2"}))
+			tree := Hypertree{root, root.Children[0]}
+			if root = tree.Normalize(); len(tree) != 1 {
+				t.Fatalf("normalized tree has %v nodes, expected 1", len(tree))
+			}
+			if sat {
+				root.Table.AddTuple([]int{1, 2, 3})
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			y, _ := NewYannakakis(root, mode)
+			res := y.reduce(ctx, root)
+			if ctx.Err() != nil {
+				t.Errorf("%s: reduce of a single node did not return", mode)
+			} else if res != sat {
+				t.Errorf("%s: reduce = %v, expected %v", mode, res, sat)
+			}
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package decomp
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dmlongo/callidus/csp"
+	"github.com/dmlongo/callidus/db"
+)
+
+// ymca runs every phase of Yannakakis' algorithm with one agent per node of
+// the tree. Agents exchange relations as messages, and a director collects
+// the outcome of each phase and stops all agents as soon as one of them
+// finds an empty relation.
+type ymca struct {
+	yResults
+}
+
+func (y *ymca) Solve(ctx context.Context) (csp.Solution, bool, error) {
+	return y.solve(ctx, y)
+}
+
+func (y *ymca) AllSolutions(ctx context.Context) ([]csp.Solution, error) {
+	return y.allSolutions(ctx, y)
+}
+
+func (y *ymca) reduce(ctx context.Context, root *Node) bool {
+	return newDirector(root).run(ctx, (*yAgent).reduce)
+}
+
+func (y *ymca) fullyReduce(ctx context.Context, root *Node) {
+	newDirector(root).run(ctx, (*yAgent).fullyReduce)
+}
+
+func (y *ymca) joinUpwards(ctx context.Context, root *Node) ([]string, db.Relation) {
+	newDirector(root).run(ctx, (*yAgent).joinUpwards)
+	return root.bag, root.Table
+}
+
+// Message exchanged by agents
+type Message interface {
+	Content() db.Relation
+}
+
+type semijoinMsg struct {
+	rel db.Relation
+}
+
+func (msg *semijoinMsg) Content() db.Relation {
+	return msg.rel
+}
+
+type joinMsg struct {
+	rel db.Relation
+}
+
+func (msg *joinMsg) Content() db.Relation {
+	return msg.rel
+}
+
+// director sets up the agents of a tree and runs them one phase at a time
+type director struct {
+	agents  []*yAgent
+	results chan bool
+	stop    chan struct{}
+}
+
+func newDirector(root *Node) *director {
+	d := &director{}
+	agentOf := make(map[*Node]*yAgent)
+	for _, n := range Bfs(root) {
+		ya := &yAgent{node: n, dir: d}
+		if n != root {
+			ya.parent = agentOf[n.Parent]
+			ya.parent.children = append(ya.parent.children, ya)
+		}
+		agentOf[n] = ya
+		d.agents = append(d.agents, ya)
+	}
+	return d
+}
+
+// run a phase on every agent and wait for all of them to return. It returns
+// false as soon as some agent reports an empty relation or ctx is done, and
+// stops the others.
+func (d *director) run(ctx context.Context, phase func(ya *yAgent)) bool {
+	d.results = make(chan bool, len(d.agents))
+	d.stop = make(chan struct{})
+	for _, ya := range d.agents {
+		// every agent receives at most one message from each neighbour
+		ya.inbox = make(chan Message, len(ya.children)+1)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(len(d.agents))
+	for _, ya := range d.agents {
+		go func(ya *yAgent) {
+			defer wg.Done()
+			phase(ya)
+		}(ya)
+	}
+	outcome := true
+	for i := 0; i < len(d.agents) && outcome; i++ {
+		select {
+		case outcome = <-d.results:
+		case <-ctx.Done():
+			outcome = false
+		}
+	}
+	close(d.stop)
+	wg.Wait()
+	return outcome
+}
+
+// yAgent runs the operations of Yannakakis' algorithm on a node
+type yAgent struct {
+	node     *Node
+	parent   *yAgent
+	children []*yAgent
+	inbox    chan Message
+	dir      *director
+}
+
+func (ya *yAgent) isRoot() bool {
+	return ya.parent == nil
+}
+
+// receive the next message, or nothing if the director stops the phase
+func (ya *yAgent) receive() (Message, bool) {
+	select {
+	case msg := <-ya.inbox:
+		return msg, true
+	case <-ya.dir.stop:
+		return nil, false
+	}
+}
+
+func (ya *yAgent) report(outcome bool) {
+	ya.dir.results <- outcome
+}
+
+// reduce semijoins the relations of the children into the one of the node,
+// and then sends it to the parent
+func (ya *yAgent) reduce() {
+	if ya.node.Table.Empty() {
+		ya.report(false)
+		return
+	}
+	for range ya.children {
+		msg, ok := ya.receive()
+		if !ok {
+			return
+		}
+		db.Semijoin(ya.node.Table, msg.Content())
+		if ya.node.Table.Empty() {
+			ya.report(false)
+			return
+		}
+	}
+	if !ya.isRoot() {
+		ya.parent.inbox <- &semijoinMsg{rel: ya.node.Table}
+	}
+	ya.report(true)
+}
+
+// fullyReduce semijoins the relation of the parent into the one of the node,
+// and then sends it to the children (after reduce)
+func (ya *yAgent) fullyReduce() {
+	if !ya.isRoot() {
+		msg, ok := ya.receive()
+		if !ok {
+			return
+		}
+		db.Semijoin(ya.node.Table, msg.Content())
+	}
+	for _, child := range ya.children {
+		child.inbox <- &semijoinMsg{rel: ya.node.Table}
+	}
+	ya.report(true)
+}
+
+// joinUpwards joins the relations of the children with the one of the node,
+// and then sends it to the parent (after fullyReduce)
+func (ya *yAgent) joinUpwards() {
+	for range ya.children {
+		msg, ok := ya.receive()
+		if !ok {
+			return
+		}
+		rel := db.Join(ya.node.Table, msg.Content())
+		ya.node.SetBag(rel.Attributes())
+		ya.node.Table = rel
+	}
+	if !ya.isRoot() {
+		ya.parent.inbox <- &joinMsg{rel: ya.node.Table}
+	}
+	ya.report(true)
+}